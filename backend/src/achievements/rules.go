@@ -0,0 +1,250 @@
+// achievements/rules.go
+package achievements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A badge's criteria is a rule of conditions joined by "and", each comparing a
+// metric of the learner with a number:
+//
+//	streak >= 30
+//	forum_upvotes >= 100 and level >= 5
+//	lessons_completed in course 3 == all
+//
+// "in course N" narrows a metric to one course, and "all" stands for
+// everything there is to do in it, such as every lesson of the course.
+
+// Metrics a rule can test
+const (
+	Streak           = "streak"            // the current streak, in days
+	LongestStreak    = "longest_streak"    // the longest streak ever, in days
+	XP               = "xp"                // all-time XP
+	Level            = "level"             // the level all-time XP reaches
+	LessonsCompleted = "lessons_completed" // lessons finished, in all or in a course
+	ForumUpvotes     = "forum_upvotes"     // upvotes from others on the learner's threads and posts
+)
+
+// metrics lists the metrics and whether each can be narrowed to a course
+var metrics = map[string]bool{
+	Streak:           false,
+	LongestStreak:    false,
+	XP:               false,
+	Level:            false,
+	LessonsCompleted: true,
+	ForumUpvotes:     false,
+}
+
+// Comparison operators
+var operators = map[string]func(a, b int) bool{
+	">=": func(a, b int) bool { return a >= b },
+	">":  func(a, b int) bool { return a > b },
+	"==": func(a, b int) bool { return a == b },
+	"<=": func(a, b int) bool { return a <= b },
+	"<":  func(a, b int) bool { return a < b },
+}
+
+// Condition compares one metric of a learner with a value
+type Condition struct {
+	Metric   string
+	CourseID uint // the course the metric is narrowed to, or 0
+	Op       string
+	Value    int
+	All      bool // compared with everything there is rather than Value
+}
+
+// Rule holds when all of its conditions do
+type Rule struct {
+	Conditions []Condition
+}
+
+// Facts are what a rule is evaluated against: a learner's metrics
+type Facts interface {
+	// Value returns a metric, in a course if courseID isn't 0
+	Value(metric string, courseID uint) (int, error)
+	// Total returns what "all" stands for: the most a metric can reach in a course
+	Total(metric string, courseID uint) (int, error)
+}
+
+// Criteria is what Badge.Criteria holds: the rule and whether an earned badge
+// is minted as an NFT to the learner's wallet
+type Criteria struct {
+	Rule string `json:"rule"`
+	Mint bool   `json:"mint"`
+}
+
+// ParseCriteria reads a badge's stored criteria and parses its rule
+func ParseCriteria(stored string) (Criteria, Rule, error) {
+	var criteria Criteria
+	if err := json.Unmarshal([]byte(stored), &criteria); err != nil {
+		return criteria, Rule{}, errors.New("criteria must be a JSON object with a rule")
+	}
+	rule, err := Parse(criteria.Rule)
+	return criteria, rule, err
+}
+
+// Parse reads a rule
+func Parse(text string) (Rule, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return Rule{}, err
+	}
+	if len(tokens) == 0 {
+		return Rule{}, errors.New("rule is empty")
+	}
+
+	var rule Rule
+	for len(tokens) > 0 {
+		var c Condition
+		if c, tokens, err = parseCondition(tokens); err != nil {
+			return Rule{}, err
+		}
+		rule.Conditions = append(rule.Conditions, c)
+		if len(tokens) == 0 {
+			break
+		}
+		if tokens[0] != "and" {
+			return Rule{}, fmt.Errorf("expected \"and\" but found %q", tokens[0])
+		}
+		tokens = tokens[1:]
+		if len(tokens) == 0 {
+			return Rule{}, errors.New("rule ends with \"and\"")
+		}
+	}
+	return rule, nil
+}
+
+// parseCondition reads one condition from the start of tokens, returning the rest
+func parseCondition(tokens []string) (Condition, []string, error) {
+	var c Condition
+	c.Metric = tokens[0]
+	scoped, ok := metrics[c.Metric]
+	if !ok {
+		return c, nil, fmt.Errorf("unknown metric %q", c.Metric)
+	}
+	tokens = tokens[1:]
+
+	if len(tokens) >= 1 && tokens[0] == "in" {
+		if !scoped {
+			return c, nil, fmt.Errorf("%s can't be narrowed to a course", c.Metric)
+		}
+		if len(tokens) < 3 || tokens[1] != "course" {
+			return c, nil, fmt.Errorf("expected \"in course\" and a course ID after %s", c.Metric)
+		}
+		id, err := strconv.ParseUint(tokens[2], 10, 64)
+		if err != nil || id == 0 {
+			return c, nil, fmt.Errorf("%q is not a course ID", tokens[2])
+		}
+		c.CourseID = uint(id)
+		tokens = tokens[3:]
+	}
+
+	if len(tokens) < 2 {
+		return c, nil, fmt.Errorf("expected a comparison after %s", c.Metric)
+	}
+	if _, ok := operators[tokens[0]]; !ok {
+		return c, nil, fmt.Errorf("unknown comparison %q", tokens[0])
+	}
+	c.Op = tokens[0]
+	if tokens[1] == "all" {
+		if c.CourseID == 0 {
+			return c, nil, errors.New("\"all\" needs the metric narrowed to a course")
+		}
+		c.All = true
+	} else {
+		value, err := strconv.Atoi(tokens[1])
+		if err != nil || value < 0 {
+			return c, nil, fmt.Errorf("%q is not a whole number", tokens[1])
+		}
+		c.Value = value
+	}
+	return c, tokens[2:], nil
+}
+
+// tokenize splits a rule into words, numbers and comparison operators
+func tokenize(text string) ([]string, error) {
+	var tokens []string
+	runes := []rune(strings.ToLower(text))
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case strings.ContainsRune("<>=", r):
+			start := i
+			for i < len(runes) && strings.ContainsRune("<>=", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			return nil, fmt.Errorf("unexpected %q in rule", r)
+		}
+	}
+	return tokens, nil
+}
+
+// String writes the rule in its canonical form
+func (r Rule) String() string {
+	parts := make([]string, len(r.Conditions))
+	for i, c := range r.Conditions {
+		metric := c.Metric
+		if c.CourseID != 0 {
+			metric += fmt.Sprintf(" in course %d", c.CourseID)
+		}
+		value := strconv.Itoa(c.Value)
+		if c.All {
+			value = "all"
+		}
+		parts[i] = fmt.Sprintf("%s %s %s", metric, c.Op, value)
+	}
+	return strings.Join(parts, " and ")
+}
+
+// Uses reports whether the rule tests any of the metrics, so a change to
+// other metrics can't make it hold
+func (r Rule) Uses(metrics ...string) bool {
+	for _, c := range r.Conditions {
+		for _, m := range metrics {
+			if c.Metric == m {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Eval reports whether the rule holds for facts. It stops at the first
+// condition that doesn't. "all" of nothing never holds: a course without
+// lessons can't be completed.
+func (r Rule) Eval(facts Facts) (bool, error) {
+	for _, c := range r.Conditions {
+		value, err := facts.Value(c.Metric, c.CourseID)
+		if err != nil {
+			return false, err
+		}
+		want := c.Value
+		if c.All {
+			if want, err = facts.Total(c.Metric, c.CourseID); err != nil {
+				return false, err
+			}
+			if want == 0 {
+				return false, nil
+			}
+		}
+		if !operators[c.Op](value, want) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,220 @@
+package achievements
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text      string
+		want      []Condition
+		canonical string // String of the parsed rule, if not text
+	}{
+		{"streak >= 30", []Condition{{Metric: Streak, Op: ">=", Value: 30}}, ""},
+		{"longest_streak > 6", []Condition{{Metric: LongestStreak, Op: ">", Value: 6}}, ""},
+		{"xp == 0", []Condition{{Metric: XP, Op: "==", Value: 0}}, ""},
+		{"level <= 5", []Condition{{Metric: Level, Op: "<=", Value: 5}}, ""},
+		{"forum_upvotes < 10", []Condition{{Metric: ForumUpvotes, Op: "<", Value: 10}}, ""},
+		{"lessons_completed >= 12", []Condition{{Metric: LessonsCompleted, Op: ">=", Value: 12}}, ""},
+		{"lessons_completed in course 3 >= 4", []Condition{{Metric: LessonsCompleted, CourseID: 3, Op: ">=", Value: 4}}, ""},
+		{"lessons_completed in course 3 == all", []Condition{{Metric: LessonsCompleted, CourseID: 3, Op: "==", All: true}}, ""},
+		{
+			"forum_upvotes >= 100 and level >= 5",
+			[]Condition{{Metric: ForumUpvotes, Op: ">=", Value: 100}, {Metric: Level, Op: ">=", Value: 5}},
+			"",
+		},
+		{
+			"streak>=7 and lessons_completed in course 12 == all and xp > 1000",
+			[]Condition{
+				{Metric: Streak, Op: ">=", Value: 7},
+				{Metric: LessonsCompleted, CourseID: 12, Op: "==", All: true},
+				{Metric: XP, Op: ">", Value: 1000},
+			},
+			"streak >= 7 and lessons_completed in course 12 == all and xp > 1000",
+		},
+		{"  STREAK   >=  30 ", []Condition{{Metric: Streak, Op: ">=", Value: 30}}, "streak >= 30"},
+		{"Lessons_Completed In Course 3 == ALL", []Condition{{Metric: LessonsCompleted, CourseID: 3, Op: "==", All: true}}, "lessons_completed in course 3 == all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			rule, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(rule.Conditions, tt.want) {
+				t.Errorf("Conditions = %+v, want %+v", rule.Conditions, tt.want)
+			}
+
+			canonical := tt.canonical
+			if canonical == "" {
+				canonical = tt.text
+			}
+			if got := rule.String(); got != canonical {
+				t.Errorf("String = %q, want %q", got, canonical)
+			}
+
+			// The canonical form parses back to the same rule
+			again, err := Parse(rule.String())
+			if err != nil {
+				t.Fatalf("Parse(String): %v", err)
+			}
+			if !reflect.DeepEqual(again, rule) {
+				t.Errorf("Parse(String) = %+v, want %+v", again, rule)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // part of the error
+	}{
+		{"", "rule is empty"},
+		{"   ", "rule is empty"},
+		{"karma >= 3", `unknown metric "karma"`},
+		{"streak", "expected a comparison after streak"},
+		{"streak >=", "expected a comparison after streak"},
+		{"streak => 3", `unknown comparison "=>"`},
+		{"streak != 3", `unexpected '!'`},
+		{"streak = 3", `unknown comparison "="`},
+		{"streak >= -3", `unexpected '-'`},
+		{"streak >= 3.5", `unexpected '.'`},
+		{"streak >= thirty", `"thirty" is not a whole number`},
+		{"streak >= 99999999999999999999", "is not a whole number"},
+		{"streak >= 3 level >= 2", `expected "and" but found "level"`},
+		{"streak >= 3 or level >= 2", `expected "and" but found "or"`},
+		{"streak >= 3 and", `rule ends with "and"`},
+		{"and streak >= 3", `unknown metric "and"`},
+		{"streak in course 3 >= 2", "streak can't be narrowed to a course"},
+		{"lessons_completed in 3 >= 2", `expected "in course" and a course ID`},
+		{"lessons_completed in course", `expected "in course" and a course ID`},
+		{"lessons_completed in course 0 >= 2", `"0" is not a course ID`},
+		{"lessons_completed in course three >= 2", `"three" is not a course ID`},
+		{"lessons_completed == all", `"all" needs the metric narrowed to a course`},
+		{"xp >= all", `"all" needs the metric narrowed to a course`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			_, err := Parse(tt.text)
+			if err == nil {
+				t.Fatal("Parse succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCriteria(t *testing.T) {
+	criteria, rule, err := ParseCriteria(`{"rule": "streak >= 7", "mint": true}`)
+	if err != nil || !criteria.Mint || rule.String() != "streak >= 7" {
+		t.Errorf("ParseCriteria = %+v, %v, %v", criteria, rule, err)
+	}
+	if _, _, err := ParseCriteria(`streak >= 7`); err == nil {
+		t.Error("ParseCriteria accepted a bare rule, want JSON")
+	}
+	if _, _, err := ParseCriteria(`{"mint": true}`); err == nil {
+		t.Error("ParseCriteria accepted criteria without a rule")
+	}
+}
+
+func TestUses(t *testing.T) {
+	rule, err := Parse("forum_upvotes >= 100 and level >= 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rule.Uses(Level) || !rule.Uses(Streak, ForumUpvotes) {
+		t.Error("Uses missed a metric the rule tests")
+	}
+	if rule.Uses(Streak, XP) || rule.Uses() {
+		t.Error("Uses reported a metric the rule doesn't test")
+	}
+}
+
+// facts is a learner's metrics, keyed by metric and course
+type facts struct {
+	values map[Condition]int // Metric and CourseID set
+	totals map[Condition]int
+	err    error
+}
+
+func (f facts) Value(metric string, courseID uint) (int, error) {
+	return f.values[Condition{Metric: metric, CourseID: courseID}], f.err
+}
+
+func (f facts) Total(metric string, courseID uint) (int, error) {
+	return f.totals[Condition{Metric: metric, CourseID: courseID}], f.err
+}
+
+func TestEval(t *testing.T) {
+	learner := facts{
+		values: map[Condition]int{
+			{Metric: Streak}:                        30,
+			{Metric: Level}:                         4,
+			{Metric: ForumUpvotes}:                  100,
+			{Metric: LessonsCompleted}:              15,
+			{Metric: LessonsCompleted, CourseID: 3}: 10,
+			{Metric: LessonsCompleted, CourseID: 4}: 2,
+			{Metric: LessonsCompleted, CourseID: 5}: 0,
+		},
+		totals: map[Condition]int{
+			{Metric: LessonsCompleted, CourseID: 3}: 10,
+			{Metric: LessonsCompleted, CourseID: 4}: 8,
+		},
+	}
+
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{"streak >= 30", true},
+		{"streak >= 31", false},
+		{"streak > 29", true},
+		{"streak > 30", false},
+		{"streak == 30", true},
+		{"streak <= 30", true},
+		{"streak < 30", false},
+		{"xp >= 0", true}, // a metric the learner has none of is 0
+		{"forum_upvotes >= 100 and level >= 5", false},
+		{"forum_upvotes >= 100 and level >= 4", true},
+		{"lessons_completed >= 15", true},
+		{"lessons_completed in course 3 == all", true},
+		{"lessons_completed in course 4 == all", false},
+		{"lessons_completed in course 4 < all", true},
+		{"lessons_completed in course 3 >= all and streak >= 7", true},
+		// "all" of a course without lessons never holds
+		{"lessons_completed in course 5 == all", false},
+		{"lessons_completed in course 5 >= all", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := rule.Eval(learner)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	broken := errors.New("database is down")
+	for _, text := range []string{"streak >= 1", "lessons_completed in course 3 == all"} {
+		rule, _ := Parse(text)
+		if _, err := rule.Eval(facts{err: broken}); !errors.Is(err, broken) {
+			t.Errorf("Eval(%q) error = %v, want the facts' error", text, err)
+		}
+	}
+}
@@ -4,13 +4,116 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 )
 
 var HeklaRPCURL = os.Getenv("HEKLA_RPC_URL")
 
+// BadgeNFTAddress is the BadgeNFT contract earned badges are minted on; when
+// empty, badges aren't minted
+var BadgeNFTAddress = os.Getenv("BADGE_NFT_ADDRESS")
+
+// JWTSecret is the HMAC key used to sign and verify access tokens
+var JWTSecret = os.Getenv("JWT_SECRET")
+
+// AccessTokenTTL is how long an issued access token stays valid
+var AccessTokenTTL = 15 * time.Minute
+
+// RefreshTokenTTL is how long a session survives without being refreshed
+var RefreshTokenTTL = 30 * 24 * time.Hour
+
+// TrustProxyHeaders makes X-Forwarded-For the source of client IPs; only
+// enable it behind a proxy that overwrites the header
+var TrustProxyHeaders = os.Getenv("TRUST_PROXY_HEADERS") == "true"
+
+// SIWEDomain is the domain wallet sign-in messages must be issued for
+var SIWEDomain = getEnv("SIWE_DOMAIN", "localhost:3000")
+
+// SignInNonceTTL is how long a wallet has to sign a nonce before it expires
+var SignInNonceTTL = 10 * time.Minute
+
+// SignInNonceMaxPerIP caps the unused, unexpired nonces one IP may hold, so the
+// unauthenticated nonce routes can't fill the table
+var SignInNonceMaxPerIP = 20
+
+// CORSOrigins lists the browser origins allowed to call the API, comma separated ("*" allows any)
+var CORSOrigins = getEnv("CORS_ORIGINS", "http://localhost:3000")
+
+// AdminEmails lists accounts promoted to admin at startup, comma separated
+var AdminEmails = os.Getenv("ADMIN_EMAILS")
+
+// AppBaseURL is the frontend address used to build links in emails
+var AppBaseURL = getEnv("APP_BASE_URL", "http://localhost:3000")
+
+// Mail settings; MailDriver is "smtp" or "outbox"
+var (
+	MailDriver    = getEnv("MAIL_DRIVER", "outbox")
+	MailFrom      = getEnv("MAIL_FROM", "Delingo <no-reply@delingo.local>")
+	MailOutboxDir = getEnv("MAIL_OUTBOX_DIR", "outbox")
+	SMTPHost      = os.Getenv("SMTP_HOST")
+	SMTPPort      = getEnv("SMTP_PORT", "587")
+	SMTPUsername  = os.Getenv("SMTP_USERNAME")
+	SMTPPassword  = os.Getenv("SMTP_PASSWORD")
+)
+
+// EmailVerificationTTL and PasswordResetTTL bound how long emailed links work
+var (
+	EmailVerificationTTL = 24 * time.Hour
+	PasswordResetTTL     = time.Hour
+)
+
+// Blob storage settings; StorageDriver is "local" or "s3". The s3 driver works
+// with AWS and with S3-compatible servers such as MinIO.
+var (
+	StorageDriver   = getEnv("STORAGE_DRIVER", "local")
+	StorageDir      = getEnv("STORAGE_DIR", "uploads")
+	StorageBaseURL  = getEnv("STORAGE_BASE_URL", "http://localhost:8080/uploads")
+	S3Endpoint      = getEnv("S3_ENDPOINT", "http://localhost:9000")
+	S3Region        = getEnv("S3_REGION", "us-east-1")
+	S3Bucket        = os.Getenv("S3_BUCKET")
+	S3AccessKey     = os.Getenv("S3_ACCESS_KEY")
+	S3SecretKey     = os.Getenv("S3_SECRET_KEY")
+	S3PublicURL     = os.Getenv("S3_PUBLIC_URL") // defaults to <endpoint>/<bucket>
+	S3PathStyle     = getEnv("S3_PATH_STYLE", "true") == "true"
+	MaxAvatarUpload = int64(5 << 20)
+)
+
+// TOTPIssuer names the account in authenticator apps
+var TOTPIssuer = getEnv("TOTP_ISSUER", "Delingo")
+
+// TwoFactorTokenTTL is how long a user has to enter their code after their password
+var TwoFactorTokenTTL = 5 * time.Minute
+
+// Login throttling: after MaxFailures failed attempts within FailureWindow an
+// account or IP is locked for LockoutBase, doubling with each further failure
+// up to LockoutMax
+var (
+	LoginMaxAccountFailures = 5
+	LoginMaxIPFailures      = 20
+	LoginFailureWindow      = 15 * time.Minute
+	LoginLockoutBase        = time.Minute
+	LoginLockoutMax         = time.Hour
+)
+
 func LoadConfig() {
 	HeklaRPCURL = os.Getenv("HEKLA_RPC_URL")
 	if HeklaRPCURL == "" {
 		log.Fatal("HEKLA_RPC_URL is required")
 	}
 }
+
+// LoadAuthConfig reads the settings needed to issue and verify tokens
+func LoadAuthConfig() {
+	JWTSecret = os.Getenv("JWT_SECRET")
+	if JWTSecret == "" {
+		log.Fatal("JWT_SECRET is required")
+	}
+}
+
+// getEnv returns the environment variable or fallback when it is unset
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,401 @@
+package controllers
+
+import (
+	"Delingo/src/achievements"
+	"Delingo/src/config"
+	"Delingo/src/events"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errMintStatus is returned when a mint isn't in the status a report moves it from
+var errMintStatus = errors.New("badge mint is in the wrong status")
+
+// learnerFacts are a learner's metrics for badge rules, each loaded once
+type learnerFacts struct {
+	userID int
+	values map[string]int
+}
+
+// GET /content/badges - Every badge, with its criteria
+func GetBadges(c *gin.Context) {
+	badges := []models.Badge{}
+	if err := utils.GormDB.Order("id").Find(&badges).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve badges"})
+		return
+	}
+	c.JSON(http.StatusOK, badges)
+}
+
+// POST /content/badges - Add a badge, earned when its rule holds. Learners
+// earn it as their metrics next change; nobody is awarded it on creation.
+func CreateBadge(c *gin.Context) {
+	var badge models.Badge
+	if !bindBadge(c, &badge) {
+		return
+	}
+	if err := utils.GormDB.Create(&badge).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create badge"})
+		return
+	}
+	c.JSON(http.StatusCreated, badge)
+}
+
+// PUT /content/badges/:id - Replace a badge's details and criteria. Badges
+// already earned are kept.
+func UpdateBadge(c *gin.Context) {
+	id, ok := pathID(c, "badge")
+	if !ok {
+		return
+	}
+	var badge models.Badge
+	err := utils.GormDB.First(&badge, id).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Badge not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve badge"})
+		return
+	}
+	if !bindBadge(c, &badge) {
+		return
+	}
+	if err := utils.GormDB.Save(&badge).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update badge"})
+		return
+	}
+	c.JSON(http.StatusOK, badge)
+}
+
+// DELETE /content/badges/:id - Delete a badge, taking it from everyone who earned it
+func DeleteBadge(c *gin.Context) {
+	id, ok := pathID(c, "badge")
+	if !ok {
+		return
+	}
+	result := utils.GormDB.Delete(&models.Badge{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete badge"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Badge not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Badge deleted"})
+}
+
+// bindBadge reads a badge's details into badge, checking its rule and storing
+// it in canonical form. On failure it writes the response and returns false.
+func bindBadge(c *gin.Context, badge *models.Badge) bool {
+	var input struct {
+		Name        string                `json:"name" binding:"required"`
+		Description string                `json:"description"`
+		Criteria    achievements.Criteria `json:"criteria"`
+		TokenID     uint64                `json:"token_id"`
+		TokenURI    string                `json:"token_uri"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A name is required"})
+		return false
+	}
+	if utf8.RuneCountInString(input.Name) > 100 || utf8.RuneCountInString(input.Description) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be at most 100 and description at most 500 characters"})
+		return false
+	}
+	rule, err := achievements.Parse(input.Criteria.Rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "criteria.rule: " + err.Error()})
+		return false
+	}
+	input.Criteria.Rule = rule.String()
+	criteria, err := json.Marshal(input.Criteria)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save criteria"})
+		return false
+	}
+
+	badge.Name = strings.TrimSpace(input.Name)
+	badge.Description = strings.TrimSpace(input.Description)
+	badge.Criteria = string(criteria)
+	badge.TokenID = input.TokenID
+	badge.TokenURI = input.TokenURI
+	return true
+}
+
+// GET /admin/badge-mints - Badge mints waiting to be sent, or with ?status=
+// sent or failed, oldest first
+func GetBadgeMints(c *gin.Context) {
+	cursor, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	status := c.DefaultQuery("status", models.MintPending)
+	if status != models.MintPending && status != models.MintSent && status != models.MintFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, sent or failed"})
+		return
+	}
+
+	query := utils.GormDB.Where("status = ?", status).Order("id").Limit(limit + 1)
+	if cursor > 0 {
+		query = query.Where("id > ?", cursor)
+	}
+	mints := []models.BadgeMint{}
+	if err := query.Find(&mints).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve badge mints"})
+		return
+	}
+
+	var next *string
+	if len(mints) > limit {
+		mints = mints[:limit]
+		next = cursorString(mints[limit-1].ID)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": mints, "next_cursor": next})
+}
+
+// PUT /admin/badge-mints/:id - Report a mint: sent with its tx_hash, failed
+// with an error, or back to pending to try a failed one again
+func UpdateBadgeMint(c *gin.Context) {
+	id, ok := pathID(c, "badge mint")
+	if !ok {
+		return
+	}
+	var input struct {
+		Status string `json:"status" binding:"required"`
+		TxHash string `json:"tx_hash"`
+		Error  string `json:"error"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A status is required"})
+		return
+	}
+	changes := map[string]interface{}{"status": input.Status, "tx_hash": "", "error": ""}
+	from := models.MintPending
+	switch input.Status {
+	case models.MintSent:
+		if _, err := hexutil.Decode(input.TxHash); err != nil || len(input.TxHash) != 66 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tx_hash must be a 0x-prefixed transaction hash"})
+			return
+		}
+		changes["tx_hash"] = strings.ToLower(input.TxHash)
+	case models.MintFailed:
+		if strings.TrimSpace(input.Error) == "" || utf8.RuneCountInString(input.Error) > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An error of at most 500 characters is required"})
+			return
+		}
+		changes["error"] = strings.TrimSpace(input.Error)
+	case models.MintPending:
+		from = models.MintFailed
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be sent, failed or pending"})
+		return
+	}
+
+	var mint models.BadgeMint
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&mint, id).Error; err != nil {
+			return err
+		}
+		if mint.Status != from {
+			return errMintStatus
+		}
+		return tx.Model(&mint).Updates(changes).Error
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Badge mint not found"})
+		return
+	case err == errMintStatus:
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Only a %s mint can be marked %s", from, input.Status)})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update badge mint"})
+		return
+	}
+
+	recordAudit(c, models.AuditBadgeMint, mint.UserID, models.JSONMap{"mint_id": mint.ID, "status": input.Status, "tx_hash": changes["tx_hash"]})
+	c.JSON(http.StatusOK, mint)
+}
+
+// evaluateBadges awards a learner every badge they haven't earned whose rule
+// tests one of the metrics that just changed and now holds. Failures are
+// logged: what changed the metrics is already saved.
+func evaluateBadges(userID int, changed ...string) {
+	var badges []models.Badge
+	err := utils.GormDB.
+		Where("NOT EXISTS (SELECT 1 FROM user_badges WHERE user_badges.badge_id = badges.id AND user_badges.user_id = ?)", userID).
+		Order("id").Find(&badges).Error
+	if err != nil {
+		log.Println("Error loading badges:", err)
+		return
+	}
+
+	facts := &learnerFacts{userID: userID, values: map[string]int{}}
+	for _, badge := range badges {
+		criteria, rule, err := achievements.ParseCriteria(badge.Criteria)
+		if err != nil {
+			log.Printf("Skipping badge %d with invalid criteria: %v", badge.ID, err)
+			continue
+		}
+		if !rule.Uses(changed...) {
+			continue
+		}
+		holds, err := rule.Eval(facts)
+		if err != nil {
+			log.Println("Error evaluating badge criteria:", err)
+			return
+		}
+		if holds {
+			awardBadge(userID, badge, criteria, rule)
+		}
+	}
+}
+
+// awardBadge records a badge as earned and, if it is minted and the learner
+// has a verified Ethereum wallet, queues the mint with it. The activity and
+// event follow once it is committed.
+func awardBadge(userID int, badge models.Badge, criteria achievements.Criteria, rule achievements.Rule) {
+	now := time.Now()
+	awarded := false
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		earned := models.UserBadge{UserID: userID, BadgeID: badge.ID, EarnedAt: now}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&earned)
+		if created.Error != nil || created.RowsAffected == 0 {
+			return created.Error
+		}
+		awarded = true
+		if !criteria.Mint || config.BadgeNFTAddress == "" {
+			return nil
+		}
+
+		var wallet models.UserIdentity
+		err := tx.Where("user_id = ? AND provider = ? AND verified_at IS NOT NULL", userID, models.ProviderEthereum).
+			Order("verified_at DESC").Limit(1).Find(&wallet).Error
+		if err != nil || wallet.ID == 0 {
+			return err
+		}
+		callData, err := utils.MintBadgeCallData(wallet.Subject, badge.Name, badge.Description, rule.String())
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.BadgeMint{
+			UserID:      userID,
+			UserBadgeID: earned.ID,
+			BadgeID:     badge.ID,
+			Contract:    config.BadgeNFTAddress,
+			Address:     wallet.Subject,
+			CallData:    hexutil.Encode(callData),
+			Status:      models.MintPending,
+		}).Error
+	})
+	if err != nil {
+		log.Println("Error awarding badge:", err)
+		return
+	}
+	if !awarded {
+		return
+	}
+
+	recordActivity(utils.GormDB, userID, models.ActivityBadgeEarned, models.JSONMap{"badge_id": badge.ID, "name": badge.Name})
+	events.Publish(events.Event{Name: events.BadgeEarned, UserID: userID, At: now, Data: map[string]interface{}{
+		"badge_id": badge.ID, "name": badge.Name,
+	}})
+}
+
+// Value returns one of the learner's metrics, in a course if courseID isn't 0
+func (f *learnerFacts) Value(metric string, courseID uint) (int, error) {
+	key := fmt.Sprintf("%s/%d", metric, courseID)
+	if value, ok := f.values[key]; ok {
+		return value, nil
+	}
+
+	var value int
+	var err error
+	switch metric {
+	case achievements.Streak, achievements.LongestStreak:
+		column := `"current"`
+		if metric == achievements.LongestStreak {
+			column = "longest"
+		}
+		err = utils.GormDB.Model(&models.Streak{}).Where("user_id = ?", f.userID).
+			Select("COALESCE(MAX(" + column + "), 0)").Scan(&value).Error
+	case achievements.XP:
+		err = utils.GormDB.Model(&models.XPEntry{}).Where("user_id = ?", f.userID).
+			Select("COALESCE(SUM(amount), 0)").Scan(&value).Error
+	case achievements.Level:
+		var xp int
+		if xp, err = f.Value(achievements.XP, 0); err == nil {
+			value = levelForXP(xp)
+		}
+	case achievements.LessonsCompleted:
+		query := utils.GormDB.Model(&models.Progress{}).Where("progresses.user_id = ? AND progresses.status = ?", f.userID, models.ProgressCompleted)
+		if courseID != 0 {
+			query = query.Joins("JOIN lessons ON lessons.id = progresses.lesson_id").
+				Joins("JOIN skills ON skills.id = lessons.skill_id").
+				Joins("JOIN units ON units.id = skills.unit_id").
+				Where("units.course_id = ?", courseID)
+		}
+		err = query.Select("COUNT(*)").Scan(&value).Error
+	case achievements.ForumUpvotes:
+		err = utils.SQLDB.QueryRow(`
+			SELECT COUNT(*)
+			FROM votes v
+			LEFT JOIN posts p ON p.id = v.post_id AND p.deleted_at IS NULL
+			LEFT JOIN threads t ON t.id = v.thread_id AND t.deleted_at IS NULL
+			WHERE v.deleted_at IS NULL AND v.vote_value = 1 AND v.user_id <> $1 AND (p.user_id = $1 OR t.user_id = $1)`,
+			f.userID).Scan(&value)
+	default:
+		err = fmt.Errorf("no facts for metric %q", metric)
+	}
+	if err != nil {
+		return 0, err
+	}
+	f.values[key] = value
+	return value, nil
+}
+
+// Total returns what "all" stands for in a course: its number of lessons
+func (f *learnerFacts) Total(metric string, courseID uint) (int, error) {
+	if metric != achievements.LessonsCompleted {
+		return 0, fmt.Errorf("no total for metric %q", metric)
+	}
+	var total int
+	err := utils.GormDB.Table("lessons").
+		Joins("JOIN skills ON skills.id = lessons.skill_id").
+		Joins("JOIN units ON units.id = skills.unit_id").
+		Where("units.course_id = ?", courseID).
+		Select("COUNT(*)").Scan(&total).Error
+	return total, err
+}
+
+// badgeMetricsFor names the metrics an event may have changed
+func badgeMetricsFor(e events.Event) []string {
+	switch e.Name {
+	case events.StreakExtended:
+		return []string{achievements.Streak, achievements.LongestStreak}
+	case events.XPAwarded:
+		changed := []string{achievements.XP, achievements.Level}
+		if e.Data["source"] == models.XPForumUpvote {
+			changed = append(changed, achievements.ForumUpvotes)
+		}
+		return changed
+	case events.LessonCompleted:
+		return []string{achievements.LessonsCompleted}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"Delingo/src/middleware"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PUT /admin/users/:id/role - Change a user's role
+func UpdateUserRole(c *gin.Context) {
+	adminID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || !middleware.IsValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid role is required"})
+		return
+	}
+
+	id, ok := pathID(c, "user")
+	if !ok {
+		return
+	}
+	var user models.User
+	if err := utils.GormDB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Keep admins from locking themselves out
+	if user.ID == int(adminID) && input.Role != models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot remove your own admin role"})
+		return
+	}
+
+	previousRole := user.Role
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("role", input.Role).Error; err != nil {
+			return err
+		}
+		// Tokens carry the old role, so make the user log in again
+		return revokeUserSessions(tx, user.ID, 0)
+	})
+	if err != nil {
+		log.Println("Error updating role:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	recordAudit(c, models.AuditRoleChange, user.ID, models.JSONMap{"from": previousRole, "to": input.Role})
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// GET /admin/lockouts - Recent login lockouts, newest first (?limit=, default 50)
+func GetLockoutEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	query := utils.GormDB.Order("created_at DESC").Limit(limit)
+	if scope := c.Query("scope"); scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	if key := c.Query("key"); key != "" {
+		query = query.Where("key = ?", key)
+	}
+
+	var events []models.LockoutEvent
+	if err := query.Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lockouts"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// DELETE /admin/lockouts?scope=&key= - Lift a lockout and reset its failure count
+func ClearLockout(c *gin.Context) {
+	scope, key := c.Query("scope"), c.Query("key")
+	if (scope != models.ThrottleAccount && scope != models.ThrottleIP) || key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope (account or ip) and key are required"})
+		return
+	}
+
+	result := utils.GormDB.Where("scope = ? AND key = ?", scope, key).Delete(&models.LoginThrottle{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear lockout"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No failed logins recorded for that key"})
+		return
+	}
+
+	recordAudit(c, models.AuditLockoutClear, 0, models.JSONMap{"scope": scope, "key": key})
+	c.JSON(http.StatusOK, gin.H{"message": "Lockout cleared"})
+}
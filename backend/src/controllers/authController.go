@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"Delingo/src/config"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"database/sql"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authResponse is returned by every endpoint that logs a user in
+type authResponse struct {
+	tokenPair
+	User models.User `json:"user"`
+}
+
+// POST /signup - Register an email user and log them straight in
+func Signup(c *gin.Context) {
+	var user models.User
+	if err := c.ShouldBindJSON(&user); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	status, err := createEmailUser(&user)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeAuthResponse(c, http.StatusCreated, user)
+}
+
+// POST /login - Check an email and password and start a session
+func Login(c *gin.Context) {
+	var credentials struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if credentials.Email == "" || credentials.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email and password are required"})
+		return
+	}
+
+	// Locked accounts and IPs are refused before the password is even checked
+	email := strings.ToLower(strings.TrimSpace(credentials.Email))
+	throttleKeys := loginThrottleKeys(c, email)
+	if wait, err := loginLockedFor(throttleKeys); err != nil {
+		log.Println("Error checking login throttle:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not log in"})
+		return
+	} else if wait > 0 {
+		writeLoginLocked(c, wait)
+		return
+	}
+
+	// Look up the email account
+	var user models.User
+	var hash string
+	var resetRequired bool
+	query := `SELECT u.id, u.username, u.email, u.password, u.registration_method, u.role, u.created_at, u.totp_enabled_at, u.password_reset_required
+			  FROM user_identities i JOIN users u ON u.id = i.user_id
+			  WHERE i.provider = 'email' AND i.subject = $1 AND NOT (` + pendingEmailLink + `)`
+	err := utils.SQLDB.QueryRow(query, email).
+		Scan(&user.ID, &user.Username, &user.Email, &hash, &user.RegistrationMethod, &user.Role, &user.CreatedAt, &user.TOTPEnabledAt, &resetRequired)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("Error looking up user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not log in"})
+		return
+	}
+
+	// Same answer for unknown email and wrong password so accounts can't be enumerated
+	if err == sql.ErrNoRows || !utils.CheckPasswordHash(credentials.Password, hash) {
+		recordLoginFailure(c, throttleKeys)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+	clearLoginFailures(email)
+
+	// An admin has required a new password; the old one only proves who is asking
+	if resetRequired {
+		c.JSON(http.StatusForbidden, gin.H{"error": "A new password is required; choose one via the link emailed to you"})
+		return
+	}
+
+	writeAuthResponse(c, http.StatusOK, user)
+}
+
+// twoFactorChallenge is returned instead of tokens when the login still needs a TOTP code
+type twoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	TwoFactorToken    string `json:"two_factor_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+// writeAuthResponse starts a session for user and writes its tokens with the user
+// details. Accounts with two-factor enabled get a challenge for /login/2fa instead,
+// and banned accounts are refused.
+func writeAuthResponse(c *gin.Context, status int, user models.User) {
+	if refuseBanned(c, user.ID) {
+		return
+	}
+	if user.TwoFactorEnabled() {
+		token, err := utils.GenerateTwoFactorToken(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+			return
+		}
+		c.JSON(http.StatusOK, twoFactorChallenge{
+			TwoFactorRequired: true,
+			TwoFactorToken:    token,
+			ExpiresIn:         int(config.TwoFactorTokenTTL.Seconds()),
+		})
+		return
+	}
+
+	startAuthSession(c, status, user)
+}
+
+// startAuthSession starts a session for user once every factor has been checked
+func startAuthSession(c *gin.Context, status int, user models.User) {
+	tokens, err := startSession(c, user)
+	if err != nil {
+		log.Println("Error starting session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		return
+	}
+
+	c.JSON(status, authResponse{tokenPair: tokens, User: user})
+}
+
+// issueSignInNonce stores a fresh single-use nonce for chain and returns it to
+// the client. Nonces that can no longer be used are deleted first, and an IP
+// holding too many outstanding nonces must wait for one to expire.
+func issueSignInNonce(c *gin.Context, chain string) {
+	now := time.Now()
+	err := utils.GormDB.Where("expires_at <= ? OR used_at IS NOT NULL", now).Delete(&models.AuthNonce{}).Error
+	if err != nil {
+		log.Println("Error deleting stale nonces:", err)
+	}
+
+	var outstanding []models.AuthNonce
+	err = utils.GormDB.Select("expires_at").Where("ip_address = ? AND used_at IS NULL AND expires_at > ?", c.ClientIP(), now).
+		Order("expires_at").Limit(config.SignInNonceMaxPerIP).Find(&outstanding).Error
+	if err != nil {
+		log.Println("Error counting nonces:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create nonce"})
+		return
+	}
+	if len(outstanding) >= config.SignInNonceMaxPerIP {
+		wait := outstanding[0].ExpiresAt.Sub(now)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many sign-in attempts. Try again later."})
+		return
+	}
+
+	value, err := utils.RandomHex(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create nonce"})
+		return
+	}
+
+	nonce := models.AuthNonce{
+		Nonce:     value,
+		Chain:     chain,
+		ExpiresAt: now.Add(config.SignInNonceTTL),
+		IPAddress: c.ClientIP(),
+	}
+	if err := utils.GormDB.Create(&nonce).Error; err != nil {
+		log.Println("Error saving nonce:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create nonce"})
+		return
+	}
+
+	c.JSON(http.StatusOK, nonce)
+}
+
+// consumeSignInNonce marks a nonce as used. It returns false when the nonce
+// is unknown, was issued for another chain, has expired or was already used.
+func consumeSignInNonce(value, chain string) (bool, error) {
+	result := utils.GormDB.Model(&models.AuthNonce{}).
+		Where("nonce = ? AND chain = ? AND used_at IS NULL AND expires_at > ?", value, chain, time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
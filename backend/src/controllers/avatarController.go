@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"Delingo/src/config"
+	"Delingo/src/models"
+	"Delingo/src/storage"
+	"Delingo/src/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// POST /profile/avatar - Upload a new avatar as the multipart field "avatar"
+func UploadAvatar(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Leave room for the multipart framing around the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.MaxAvatarUpload+64<<10)
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeAvatarTooLarge(c)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send the image as the multipart field \"avatar\""})
+		return
+	}
+	if file.Size > config.MaxAvatarUpload {
+		writeAvatarTooLarge(c)
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, config.MaxAvatarUpload+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	if int64(len(data)) > config.MaxAvatarUpload {
+		writeAvatarTooLarge(c)
+		return
+	}
+
+	// The type is sniffed from the bytes; the client's Content-Type isn't trusted
+	thumbnails, err := utils.ProcessAvatar(data)
+	if errors.Is(err, utils.ErrUnsupportedImage) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Each upload gets fresh keys, so caches never serve a stale avatar
+	avatars, err := storeAvatar(c.Request.Context(), userID, thumbnails)
+	if err != nil {
+		log.Println("Error storing avatar:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store avatar"})
+		return
+	}
+
+	profile, err := replaceAvatar(userID, avatars)
+	if err != nil {
+		removeAvatarFiles(avatars.Keys)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update avatar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// DELETE /profile/avatar - Remove the avatar
+func DeleteAvatar(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := replaceAvatar(userID, models.Avatars{})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove avatar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// storeAvatar uploads every thumbnail, removing the ones already written if any upload fails
+func storeAvatar(ctx context.Context, userID uint, thumbnails map[string][]byte) (models.Avatars, error) {
+	prefix, err := utils.RandomHex(8)
+	if err != nil {
+		return models.Avatars{}, err
+	}
+
+	// Upload in a fixed order so failures are reproducible
+	sizes := make([]string, 0, len(thumbnails))
+	for size := range thumbnails {
+		sizes = append(sizes, size)
+	}
+	sort.Strings(sizes)
+
+	avatars := models.Avatars{URLs: map[string]string{}}
+	for _, size := range sizes {
+		key := fmt.Sprintf("avatars/%d/%s-%s.jpg", userID, prefix, size)
+		if err := storage.Default.Put(ctx, key, thumbnails[size], "image/jpeg"); err != nil {
+			removeAvatarFiles(avatars.Keys)
+			return models.Avatars{}, err
+		}
+		avatars.Keys = append(avatars.Keys, key)
+		avatars.URLs[size] = storage.Default.URL(key)
+	}
+	return avatars, nil
+}
+
+// replaceAvatar points the profile at new thumbnails and then removes the old
+// files. The row is locked so concurrent uploads can't lose track of a file.
+func replaceAvatar(userID uint, avatars models.Avatars) (models.Profile, error) {
+	var old models.Avatars
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		var profile models.Profile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).First(&profile).Error; err != nil {
+			return err
+		}
+		old = profile.Avatars
+
+		return tx.Model(&models.Profile{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"avatar_url": avatars.URLs["large"],
+			"avatars":    avatars,
+		}).Error
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.Println("Error updating avatar:", err)
+		}
+		return models.Profile{}, err
+	}
+
+	removeAvatarFiles(old.Keys)
+	return GetUserProfileFromDB(userID)
+}
+
+// removeAvatarFiles deletes stored thumbnails. Failures only leave orphaned
+// files behind, so they are logged rather than returned.
+func removeAvatarFiles(keys []string) {
+	for _, key := range keys {
+		if err := storage.Default.Delete(context.Background(), key); err != nil {
+			log.Printf("Error deleting %s: %v", key, err)
+		}
+	}
+}
+
+// writeAvatarTooLarge answers an upload over the size limit
+func writeAvatarTooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": fmt.Sprintf("Avatar must be at most %d MB", config.MaxAvatarUpload>>20),
+	})
+}
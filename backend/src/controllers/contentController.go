@@ -0,0 +1,667 @@
+package controllers
+
+import (
+	"Delingo/src/exercises"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Authoring endpoints for course content. Every node is created at the end of
+// its parent's children; PUT .../order rearranges them.
+
+var (
+	errContentOrder        = errors.New("ids must list every child exactly once")
+	errPrerequisiteInvalid = errors.New("prerequisites must be other skills of the same course")
+	errPrerequisiteCycle   = errors.New("prerequisites can't depend on each other in a cycle")
+)
+
+// GET /content/courses - Every course, published or not
+func GetAuthoringCourses(c *gin.Context) {
+	var courses []models.Course
+	if err := utils.GormDB.Order("source_language, target_language, id").Find(&courses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve courses"})
+		return
+	}
+	c.JSON(http.StatusOK, courses)
+}
+
+// GET /content/courses/:id - A course with all of its units, skills, lessons and exercises
+func GetAuthoringCourse(c *gin.Context) {
+	courseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	course, err := loadCourseTree(uint(courseID), true)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error loading course:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve course"})
+		return
+	}
+	c.JSON(http.StatusOK, course)
+}
+
+// POST /content/courses - Start a course for a language pair, unpublished
+func CreateCourse(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		SourceLanguage string `json:"source_language"`
+		TargetLanguage string `json:"target_language"`
+		Title          string `json:"title"`
+		Description    string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	course := models.Course{
+		SourceLanguage: input.SourceLanguage,
+		TargetLanguage: input.TargetLanguage,
+		Title:          strings.TrimSpace(input.Title),
+		Description:    input.Description,
+		CreatedBy:      int(userID),
+	}
+	if err := course.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := utils.GormDB.Create(&course).Error; err != nil {
+		log.Println("Error creating course:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create course"})
+		return
+	}
+	c.JSON(http.StatusCreated, course)
+}
+
+// PATCH /content/courses/:id - Change the fields sent, including whether learners can see it
+func UpdateCourse(c *gin.Context) {
+	id, ok := pathID(c, "course")
+	if !ok {
+		return
+	}
+	var course models.Course
+	if err := utils.GormDB.First(&course, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		return
+	}
+
+	var input struct {
+		SourceLanguage *string `json:"source_language"`
+		TargetLanguage *string `json:"target_language"`
+		Title          *string `json:"title"`
+		Description    *string `json:"description"`
+		Published      *bool   `json:"published"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if input.SourceLanguage != nil {
+		course.SourceLanguage = *input.SourceLanguage
+	}
+	if input.TargetLanguage != nil {
+		course.TargetLanguage = *input.TargetLanguage
+	}
+	if input.Title != nil {
+		course.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		course.Description = *input.Description
+	}
+	if input.Published != nil {
+		course.Published = *input.Published
+	}
+	if err := course.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := utils.GormDB.Save(&course).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update course"})
+		return
+	}
+	c.JSON(http.StatusOK, course)
+}
+
+// DELETE /content/courses/:id - Delete a course and everything in it
+func DeleteCourse(c *gin.Context) {
+	deleteContent(c, &models.Course{}, "Course")
+}
+
+// POST /content/courses/:id/units - Add a unit at the end of a course
+func CreateUnit(c *gin.Context) {
+	courseID, ok := contentParent(c, &models.Course{}, "Course")
+	if !ok {
+		return
+	}
+	var input struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if !bindContentTitle(c, &input, &input.Title) {
+		return
+	}
+
+	unit := models.Unit{CourseID: courseID, Title: input.Title, Description: input.Description}
+	createContent(c, &unit, &unit.Position, "units", "course_id", courseID)
+}
+
+// PATCH /content/units/:id - Change a unit's title or description
+func UpdateUnit(c *gin.Context) {
+	id, ok := pathID(c, "unit")
+	if !ok {
+		return
+	}
+	var unit models.Unit
+	if err := utils.GormDB.First(&unit, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+		return
+	}
+	var input struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+	}
+	if !bindContentUpdate(c, &input, "title", &unit.Title, func() {
+		if input.Title != nil {
+			unit.Title = strings.TrimSpace(*input.Title)
+		}
+		if input.Description != nil {
+			unit.Description = *input.Description
+		}
+	}) {
+		return
+	}
+	saveContent(c, &unit)
+}
+
+// DELETE /content/units/:id - Delete a unit and everything in it
+func DeleteUnit(c *gin.Context) {
+	deleteContent(c, &models.Unit{}, "Unit")
+}
+
+// PUT /content/courses/:id/units/order - Put a course's units in the order of ids
+func ReorderUnits(c *gin.Context) {
+	if courseID, ok := contentParent(c, &models.Course{}, "Course"); ok {
+		reorderContent(c, "units", "course_id", courseID)
+	}
+}
+
+// POST /content/units/:id/skills - Add a skill at the end of a unit
+func CreateSkill(c *gin.Context) {
+	unitID, ok := contentParent(c, &models.Unit{}, "Unit")
+	if !ok {
+		return
+	}
+	var input struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if !bindContentTitle(c, &input, &input.Title) {
+		return
+	}
+
+	skill := models.Skill{UnitID: unitID, Title: input.Title, Description: input.Description, PrerequisiteIDs: []uint{}}
+	createContent(c, &skill, &skill.Position, "skills", "unit_id", unitID)
+}
+
+// PATCH /content/skills/:id - Change a skill's title or description
+func UpdateSkill(c *gin.Context) {
+	id, ok := pathID(c, "skill")
+	if !ok {
+		return
+	}
+	var skill models.Skill
+	if err := utils.GormDB.First(&skill, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Skill not found"})
+		return
+	}
+	var input struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+	}
+	if !bindContentUpdate(c, &input, "title", &skill.Title, func() {
+		if input.Title != nil {
+			skill.Title = strings.TrimSpace(*input.Title)
+		}
+		if input.Description != nil {
+			skill.Description = *input.Description
+		}
+	}) {
+		return
+	}
+	skill.PrerequisiteIDs = []uint{}
+	if err := utils.GormDB.Model(&models.SkillPrerequisite{}).Where("skill_id = ?", skill.ID).
+		Pluck("prerequisite_id", &skill.PrerequisiteIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save changes"})
+		return
+	}
+	saveContent(c, &skill)
+}
+
+// DELETE /content/skills/:id - Delete a skill and everything in it
+func DeleteSkill(c *gin.Context) {
+	deleteContent(c, &models.Skill{}, "Skill")
+}
+
+// PUT /content/units/:id/skills/order - Put a unit's skills in the order of ids
+func ReorderSkills(c *gin.Context) {
+	if unitID, ok := contentParent(c, &models.Unit{}, "Unit"); ok {
+		reorderContent(c, "skills", "unit_id", unitID)
+	}
+}
+
+// PUT /content/skills/:id/prerequisites - Replace the skills that must be
+// finished before this one unlocks. They must belong to the same course.
+func SetSkillPrerequisites(c *gin.Context) {
+	skillID, ok := contentParent(c, &models.Skill{}, "Skill")
+	if !ok {
+		return
+	}
+	var input struct {
+		IDs []uint `json:"ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.IDs == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids is required"})
+		return
+	}
+	prerequisites := []uint{}
+	seen := map[uint]bool{}
+	for _, id := range input.IDs {
+		if !seen[id] {
+			seen[id] = true
+			prerequisites = append(prerequisites, id)
+		}
+	}
+
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		// Every skill of the course, and the prerequisites between them
+		var courseSkills []uint
+		err := tx.Table("skills").Select("skills.id").
+			Joins("JOIN units ON units.id = skills.unit_id").
+			Where("units.course_id = (SELECT u.course_id FROM skills s JOIN units u ON u.id = s.unit_id WHERE s.id = ?)", skillID).
+			Pluck("skills.id", &courseSkills).Error
+		if err != nil {
+			return err
+		}
+		inCourse := map[uint]bool{}
+		for _, id := range courseSkills {
+			inCourse[id] = true
+		}
+		for _, id := range prerequisites {
+			if id == skillID || !inCourse[id] {
+				return errPrerequisiteInvalid
+			}
+		}
+
+		var edges []models.SkillPrerequisite
+		if err := tx.Where("skill_id IN ?", courseSkills).Find(&edges).Error; err != nil {
+			return err
+		}
+		graph := map[uint][]uint{}
+		for _, e := range edges {
+			if e.SkillID != skillID {
+				graph[e.SkillID] = append(graph[e.SkillID], e.PrerequisiteID)
+			}
+		}
+		graph[skillID] = prerequisites
+		if hasPrerequisiteCycle(graph) {
+			return errPrerequisiteCycle
+		}
+
+		if err := tx.Where("skill_id = ?", skillID).Delete(&models.SkillPrerequisite{}).Error; err != nil {
+			return err
+		}
+		for _, id := range prerequisites {
+			if err := tx.Create(&models.SkillPrerequisite{SkillID: skillID, PrerequisiteID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == errPrerequisiteInvalid || err == errPrerequisiteCycle {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error setting prerequisites:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set prerequisites"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"skill_id": skillID, "prerequisite_ids": prerequisites})
+}
+
+// POST /content/skills/:id/lessons - Add a lesson at the end of a skill
+func CreateLesson(c *gin.Context) {
+	skillID, ok := contentParent(c, &models.Skill{}, "Skill")
+	if !ok {
+		return
+	}
+	var input struct {
+		Title string `json:"title"`
+	}
+	if !bindContentTitle(c, &input, &input.Title) {
+		return
+	}
+
+	lesson := models.Lesson{SkillID: skillID, Title: input.Title}
+	createContent(c, &lesson, &lesson.Position, "lessons", "skill_id", skillID)
+}
+
+// PATCH /content/lessons/:id - Change a lesson's title
+func UpdateLesson(c *gin.Context) {
+	id, ok := pathID(c, "lesson")
+	if !ok {
+		return
+	}
+	var lesson models.Lesson
+	if err := utils.GormDB.First(&lesson, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lesson not found"})
+		return
+	}
+	var input struct {
+		Title *string `json:"title"`
+	}
+	if !bindContentUpdate(c, &input, "title", &lesson.Title, func() {
+		if input.Title != nil {
+			lesson.Title = strings.TrimSpace(*input.Title)
+		}
+	}) {
+		return
+	}
+	saveContent(c, &lesson)
+}
+
+// DELETE /content/lessons/:id - Delete a lesson and its exercises
+func DeleteLesson(c *gin.Context) {
+	deleteContent(c, &models.Lesson{}, "Lesson")
+}
+
+// PUT /content/skills/:id/lessons/order - Put a skill's lessons in the order of ids
+func ReorderLessons(c *gin.Context) {
+	if skillID, ok := contentParent(c, &models.Skill{}, "Skill"); ok {
+		reorderContent(c, "lessons", "skill_id", skillID)
+	}
+}
+
+// POST /content/lessons/:id/exercises - Add an exercise at the end of a lesson
+func CreateExercise(c *gin.Context) {
+	lessonID, ok := contentParent(c, &models.Lesson{}, "Lesson")
+	if !ok {
+		return
+	}
+	var input struct {
+		Type    string         `json:"type"`
+		Prompt  string         `json:"prompt"`
+		Content models.JSONMap `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	exercise := models.Exercise{LessonID: lessonID, Type: input.Type, Prompt: strings.TrimSpace(input.Prompt), Content: input.Content}
+	if exercise.Prompt == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prompt is required"})
+		return
+	}
+	if err := exercises.Validate(exercise.Type, exercise.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	createContent(c, &exercise, &exercise.Position, "exercises", "lesson_id", lessonID)
+}
+
+// PATCH /content/exercises/:id - Change the fields sent
+func UpdateExercise(c *gin.Context) {
+	id, ok := pathID(c, "exercise")
+	if !ok {
+		return
+	}
+	var exercise models.Exercise
+	if err := utils.GormDB.First(&exercise, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		return
+	}
+	var input struct {
+		Type    *string         `json:"type"`
+		Prompt  *string         `json:"prompt"`
+		Content *models.JSONMap `json:"content"`
+	}
+	if !bindContentUpdate(c, &input, "prompt", &exercise.Prompt, func() {
+		if input.Type != nil {
+			exercise.Type = *input.Type
+		}
+		if input.Prompt != nil {
+			exercise.Prompt = strings.TrimSpace(*input.Prompt)
+		}
+		if input.Content != nil {
+			exercise.Content = *input.Content
+		}
+	}) {
+		return
+	}
+	if err := exercises.Validate(exercise.Type, exercise.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	saveContent(c, &exercise)
+}
+
+// DELETE /content/exercises/:id - Delete an exercise
+func DeleteExercise(c *gin.Context) {
+	deleteContent(c, &models.Exercise{}, "Exercise")
+}
+
+// PUT /content/lessons/:id/exercises/order - Put a lesson's exercises in the order of ids
+func ReorderExercises(c *gin.Context) {
+	if lessonID, ok := contentParent(c, &models.Lesson{}, "Lesson"); ok {
+		reorderContent(c, "exercises", "lesson_id", lessonID)
+	}
+}
+
+// contentParent reads the :id of the node a request acts on, kind being its
+// name in errors, and checks that it exists. On failure it writes the
+// response and returns false.
+func contentParent(c *gin.Context, model interface{}, kind string) (uint, bool) {
+	id, ok := pathID(c, strings.ToLower(kind))
+	if !ok {
+		return 0, false
+	}
+	var count int64
+	if err := utils.GormDB.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + strings.ToLower(kind)})
+		return 0, false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": kind + " not found"})
+		return 0, false
+	}
+	return id, true
+}
+
+// bindContentTitle binds a new node, whose title is required. On failure it
+// writes the response and returns false.
+func bindContentTitle(c *gin.Context, input interface{}, title *string) bool {
+	if err := c.ShouldBindJSON(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return false
+	}
+	*title = strings.TrimSpace(*title)
+	if *title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return false
+	}
+	return true
+}
+
+// bindContentUpdate binds the changes to a node and applies them with apply.
+// The node's required text field, named field, may change but not be emptied.
+// On failure it writes the response and returns false.
+func bindContentUpdate(c *gin.Context, input interface{}, field string, value *string, apply func()) bool {
+	if err := c.ShouldBindJSON(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return false
+	}
+	apply()
+	if *value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": field + " can't be empty"})
+		return false
+	}
+	return true
+}
+
+// createContent saves a new node after the last of its siblings, the nodes of
+// table whose parentColumn is parentID
+func createContent(c *gin.Context, node interface{}, position *int, table, parentColumn string, parentID uint) {
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(table).Where(parentColumn+" = ?", parentID).
+			Select("COALESCE(MAX(position), 0) + 1").Scan(position).Error; err != nil {
+			return err
+		}
+		return tx.Create(node).Error
+	})
+	if err != nil {
+		log.Printf("Error creating %s: %v", table, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create content"})
+		return
+	}
+	c.JSON(http.StatusCreated, node)
+}
+
+// saveContent writes an updated node
+func saveContent(c *gin.Context, node interface{}) {
+	if err := utils.GormDB.Save(node).Error; err != nil {
+		log.Println("Error updating content:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save changes"})
+		return
+	}
+	c.JSON(http.StatusOK, node)
+}
+
+// deleteContent deletes the :id node of model; the database removes its children
+func deleteContent(c *gin.Context, model interface{}, kind string) {
+	id, ok := pathID(c, strings.ToLower(kind))
+	if !ok {
+		return
+	}
+	result := utils.GormDB.Delete(model, id)
+	if result.Error != nil {
+		log.Println("Error deleting content:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete " + strings.ToLower(kind)})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": kind + " not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": kind + " deleted"})
+}
+
+// reorderContent numbers the children of parentID in table in the order of the
+// request's ids, which must name each of them once
+func reorderContent(c *gin.Context, table, parentColumn string, parentID uint) {
+	var input struct {
+		IDs []uint `json:"ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids is required"})
+		return
+	}
+
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		var current []uint
+		if err := tx.Table(table).Where(parentColumn+" = ?", parentID).Pluck("id", &current).Error; err != nil {
+			return err
+		}
+		if !sameIDs(current, input.IDs) {
+			return errContentOrder
+		}
+		for i, id := range input.IDs {
+			if err := tx.Table(table).Where("id = ?", id).Update("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == errContentOrder {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error reordering %s: %v", table, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder content"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ids": input.IDs})
+}
+
+// sameIDs reports whether b lists exactly the IDs of a, each once
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	want := map[uint]bool{}
+	for _, id := range a {
+		want[id] = true
+	}
+	for _, id := range b {
+		if !want[id] {
+			return false
+		}
+		delete(want, id)
+	}
+	return true
+}
+
+// hasPrerequisiteCycle reports whether following prerequisites from any skill
+// of graph can lead back to it
+func hasPrerequisiteCycle(graph map[uint][]uint) bool {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[uint]int{}
+	var visit func(id uint) bool
+	visit = func(id uint) bool {
+		switch state[id] {
+		case visiting:
+			return true
+		case done:
+			return false
+		}
+		state[id] = visiting
+		for _, next := range graph[id] {
+			if visit(next) {
+				return true
+			}
+		}
+		state[id] = done
+		return false
+	}
+	for id := range graph {
+		if visit(id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// courseTree is a course as a learner sees it: the path through it, with what
+// they have finished and what is still locked. Exercises are served one lesson
+// at a time, so only their number is shown here.
+type courseTree struct {
+	ID             uint       `json:"id"`
+	SourceLanguage string     `json:"source_language"`
+	TargetLanguage string     `json:"target_language"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Units          []unitNode `json:"units"`
+}
+
+type unitNode struct {
+	ID          uint        `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Locked      bool        `json:"locked"`
+	Completed   bool        `json:"completed"`
+	Skills      []skillNode `json:"skills"`
+}
+
+type skillNode struct {
+	ID              uint         `json:"id"`
+	Title           string       `json:"title"`
+	Description     string       `json:"description"`
+	PrerequisiteIDs []uint       `json:"prerequisite_ids"`
+	Locked          bool         `json:"locked"`
+	Completed       bool         `json:"completed"`
+	Lessons         []lessonNode `json:"lessons"`
+}
+
+type lessonNode struct {
+	ID            uint   `json:"id"`
+	Title         string `json:"title"`
+	ExerciseCount int    `json:"exercise_count"`
+	Locked        bool   `json:"locked"`
+	Completed     bool   `json:"completed"`
+}
+
+// GET /courses - Published courses, filtered by ?source= and ?target= language
+func GetCourses(c *gin.Context) {
+	query := utils.GormDB.Where("published = ?", true).Order("source_language, target_language, id")
+	if source := c.Query("source"); source != "" {
+		query = query.Where("source_language = ?", source)
+	}
+	if target := c.Query("target"); target != "" {
+		query = query.Where("target_language = ?", target)
+	}
+
+	var courses []models.Course
+	if err := query.Find(&courses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve courses"})
+		return
+	}
+	c.JSON(http.StatusOK, courses)
+}
+
+// GET /courses/:id - A published course's tree, locked and unlocked for the
+// logged-in learner. Anonymous visitors see it as a new learner would.
+func GetCourse(c *gin.Context) {
+	courseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	course, err := loadCourseTree(uint(courseID), false)
+	if err == gorm.ErrRecordNotFound || (err == nil && !course.Published) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error loading course:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve course"})
+		return
+	}
+
+	completed := map[uint]bool{}
+	if userID, err := getUserFromToken(c); err == nil {
+		if completed, err = completedLessons(int(userID), course.ID); err != nil {
+			log.Println("Error loading lesson progress:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve course"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, buildCourseTree(course, completed))
+}
+
+// loadCourseTree loads a course with its units, skills and lessons in order,
+// and each skill's prerequisites. With exercises the lessons' exercises are
+// loaded in full; without, only their IDs are, for counting.
+func loadCourseTree(courseID uint, exercises bool) (models.Course, error) {
+	inOrder := func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }
+	exerciseQuery := inOrder
+	if !exercises {
+		exerciseQuery = func(db *gorm.DB) *gorm.DB { return db.Select("id", "lesson_id").Order("position, id") }
+	}
+
+	var course models.Course
+	err := utils.GormDB.
+		Preload("Units", inOrder).
+		Preload("Units.Skills", inOrder).
+		Preload("Units.Skills.Lessons", inOrder).
+		Preload("Units.Skills.Lessons.Exercises", exerciseQuery).
+		First(&course, courseID).Error
+	if err != nil {
+		return course, err
+	}
+
+	var skillIDs []uint
+	for _, unit := range course.Units {
+		for _, skill := range unit.Skills {
+			skillIDs = append(skillIDs, skill.ID)
+		}
+	}
+	var edges []models.SkillPrerequisite
+	if len(skillIDs) > 0 {
+		if err := utils.GormDB.Where("skill_id IN ?", skillIDs).Order("prerequisite_id").Find(&edges).Error; err != nil {
+			return course, err
+		}
+	}
+	prerequisites := map[uint][]uint{}
+	for _, e := range edges {
+		prerequisites[e.SkillID] = append(prerequisites[e.SkillID], e.PrerequisiteID)
+	}
+	for u := range course.Units {
+		for s := range course.Units[u].Skills {
+			skill := &course.Units[u].Skills[s]
+			skill.PrerequisiteIDs = append([]uint{}, prerequisites[skill.ID]...)
+		}
+	}
+	return course, nil
+}
+
+// buildCourseTree works out what is finished and unlocked given the lessons a
+// learner has completed. A unit opens once the one before it is finished, a
+// skill once its unit is open and its prerequisites are finished, and a lesson
+// once its skill is open and the lesson before it is finished. A skill is
+// finished when all of its lessons are; one without lessons holds nobody back.
+func buildCourseTree(course models.Course, completed map[uint]bool) courseTree {
+	tree := courseTree{
+		ID:             course.ID,
+		SourceLanguage: course.SourceLanguage,
+		TargetLanguage: course.TargetLanguage,
+		Title:          course.Title,
+		Description:    course.Description,
+		Units:          []unitNode{},
+	}
+
+	skillDone := map[uint]bool{}
+	for _, unit := range course.Units {
+		for _, skill := range unit.Skills {
+			done := true
+			for _, lesson := range skill.Lessons {
+				done = done && completed[lesson.ID]
+			}
+			skillDone[skill.ID] = done
+		}
+	}
+
+	previousDone := true
+	for _, unit := range course.Units {
+		un := unitNode{
+			ID:          unit.ID,
+			Title:       unit.Title,
+			Description: unit.Description,
+			Locked:      !previousDone,
+			Completed:   true,
+			Skills:      []skillNode{},
+		}
+		for _, skill := range unit.Skills {
+			sn := skillNode{
+				ID:              skill.ID,
+				Title:           skill.Title,
+				Description:     skill.Description,
+				PrerequisiteIDs: skill.PrerequisiteIDs,
+				Locked:          un.Locked,
+				Completed:       skillDone[skill.ID],
+				Lessons:         []lessonNode{},
+			}
+			for _, id := range skill.PrerequisiteIDs {
+				sn.Locked = sn.Locked || !skillDone[id]
+			}
+
+			lessonOpen := !sn.Locked
+			for _, lesson := range skill.Lessons {
+				sn.Lessons = append(sn.Lessons, lessonNode{
+					ID:            lesson.ID,
+					Title:         lesson.Title,
+					ExerciseCount: len(lesson.Exercises),
+					Locked:        !lessonOpen,
+					Completed:     completed[lesson.ID],
+				})
+				lessonOpen = lessonOpen && completed[lesson.ID]
+			}
+
+			un.Completed = un.Completed && sn.Completed
+			un.Skills = append(un.Skills, sn)
+		}
+		previousDone = un.Completed
+		tree.Units = append(tree.Units, un)
+	}
+	return tree
+}
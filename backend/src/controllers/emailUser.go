@@ -1,101 +1,219 @@
 package controllers
 
 import (
+	"Delingo/src/middleware"
 	"Delingo/src/models"
 	"Delingo/src/utils"
-	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gorilla/mux"
+	"github.com/gin-gonic/gin"
 )
 
-// RegisterUser handles email-based user registration
-func RegisterUser(w http.ResponseWriter, r *http.Request) {
+// POST /users - Email-based user registration
+func RegisterUser(c *gin.Context) {
 	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
+	if err := c.ShouldBindJSON(&user); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	status, err := createEmailUser(&user)
 	if err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Respond with user details
+	c.JSON(status, user)
+}
+
+// createEmailUser validates and saves a new email user with a hashed password.
+// On failure it returns the HTTP status that best describes the error.
+func createEmailUser(user *models.User) (int, error) {
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+	user.Username = strings.TrimSpace(user.Username)
+
 	// Ensure the user provides an email and password
 	if user.Email == "" || user.Password == "" {
-		http.Error(w, "Email and password are required", http.StatusBadRequest)
-		return
+		return http.StatusBadRequest, errors.New("Email and password are required")
 	}
 
-	// Clear wallet-related fields for email registration
-	user.EthereumWalletAddr = ""
-	user.SolanaWalletAddr = ""
-	user.RegistrationMethod = "email"
+	// A username is optional; without one the account gets a default
+	if user.Username != "" {
+		if status, err := checkUsername(user.Username, 0); err != nil {
+			return status, err
+		}
+	}
 
-	// Save user to the database
-	query := `INSERT INTO users (username, email, password, registration_method, created_at) 
-              VALUES ($1, $2, $3, $4, NOW()) RETURNING id`
-	err = utils.SQLDB.QueryRow(query, user.Username, user.Email, user.Password, user.RegistrationMethod).Scan(&user.ID)
+	// Refuse a second account for the same address. An unproven link to
+	// another account doesn't count: it is dropped below.
+	taken, err := emailClaimed(user.Email)
 	if err != nil {
-		http.Error(w, "Could not create user", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, errors.New("Could not create user")
+	}
+	if taken {
+		return http.StatusConflict, errors.New("Email is already registered")
 	}
 
-	// Respond with user details
-	json.NewEncoder(w).Encode(user)
+	// Never store the plain-text password
+	hash, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	user.RegistrationMethod = models.ProviderEmail
+	user.Role = models.RoleLearner
+
+	// Save the user and their email identity together
+	tx, err := utils.SQLDB.Begin()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Could not create user")
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO users (username, email, password, registration_method, role, created_at) 
+              VALUES ($1, $2, $3, $4, $5, NOW()) RETURNING id, created_at`
+	err = tx.QueryRow(query, user.Username, user.Email, hash, user.RegistrationMethod, user.Role).Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Could not create user")
+	}
+
+	if user.Username == "" {
+		user.Username = models.DefaultUsername(user.ID)
+		if _, err := tx.Exec(`UPDATE users SET username=$1 WHERE id=$2`, user.Username, user.ID); err != nil {
+			return http.StatusInternalServerError, errors.New("Could not create user")
+		}
+	}
+
+	if _, err := tx.Exec(dropPendingEmailLink, user.Email, user.ID); err != nil {
+		return http.StatusInternalServerError, errors.New("Could not create user")
+	}
+	_, err = tx.Exec(`INSERT INTO user_identities (user_id, provider, subject, created_at) VALUES ($1, $2, $3, NOW())`,
+		user.ID, models.ProviderEmail, user.Email)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Could not create user")
+	}
+
+	// Every account starts with an empty profile
+	_, err = tx.Exec(`INSERT INTO profiles (user_id, created_at, updated_at) VALUES ($1, NOW(), NOW())`, user.ID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Could not create user")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return http.StatusInternalServerError, errors.New("Could not create user")
+	}
+
+	// Ask the user to confirm they own the address
+	sendVerificationEmail(user.ID, user.Email)
+
+	// The hash stays in the database only
+	user.Password = ""
+	return http.StatusCreated, nil
 }
 
-// GetUser retrieves a user profile by ID
-func GetUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID := vars["id"]
+// checkUsername validates a username userID (0 for a new account) wants and
+// makes sure no other account has it. On failure it returns the HTTP status
+// that best describes the error.
+func checkUsername(username string, userID int) (int, error) {
+	if err := models.ValidateUsername(username, userID); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	var taken bool
+	err := utils.SQLDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE lower(username) = lower($1) AND id <> $2)`,
+		username, userID).Scan(&taken)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Could not check username")
+	}
+	if taken {
+		return http.StatusConflict, errors.New("Username is already taken")
+	}
+	return http.StatusOK, nil
+}
 
-	var user models.User
-	query := `SELECT id, username, email, wallet_address, created_at FROM users WHERE id=$1`
-	row := utils.SQLDB.QueryRow(query, userID)
-	// query := `SELECT id, username, email, ethereum_wallet_addr, solana_wallet_addr, created_at FROM users WHERE id=$1`
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.EthereumWalletAddr, &user.SolanaWalletAddr, &user.CreatedAt)
+// publicUser is what anyone may see of an account; contact details and login
+// methods stay private
+type publicUser struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GET /users/:id - Retrieve a user by ID
+func GetUser(c *gin.Context) {
+	var user publicUser
+	query := `SELECT id, username, created_at FROM users WHERE id=$1`
+	row := utils.SQLDB.QueryRow(query, c.Param("id"))
+	err := row.Scan(&user.ID, &user.Username, &user.CreatedAt)
 
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	json.NewEncoder(w).Encode(user)
+	c.JSON(http.StatusOK, user)
 }
 
-// UpdateUser updates a user's profile information
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID := vars["id"]
+// PUT /users/:id - Update a user's account details
+func UpdateUser(c *gin.Context) {
+	userID, ok := managedUserID(c)
+	if !ok {
+		return
+	}
 
 	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := c.ShouldBindJSON(&user); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user.Username = strings.TrimSpace(user.Username)
+	if status, err := checkUsername(user.Username, userID); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	query := `UPDATE users SET username=$1, email=$2 WHERE id=$3`
-	_, err = utils.SQLDB.Exec(query, user.Username, user.Email, userID)
+	// Email changes go through the identity link/unlink endpoints
+	query := `UPDATE users SET username=$1 WHERE id=$2`
+	result, err := utils.SQLDB.Exec(query, user.Username, userID)
 	if err != nil {
-		http.Error(w, "Could not update user", http.StatusInternalServerError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(user)
+	user.ID = userID
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
 }
 
-// DeleteUser deletes a user profile
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID := vars["id"]
+// managedUserID parses the :id route parameter and checks the caller may change
+// that account: it must be their own, or they must have users:manage. On failure
+// it writes the response and returns false.
+func managedUserID(c *gin.Context) (int, bool) {
+	callerID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return 0, false
+	}
 
-	query := `DELETE FROM users WHERE id=$1`
-	_, err := utils.SQLDB.Exec(query, userID)
+	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		http.Error(w, "Could not delete user", http.StatusInternalServerError)
-		return
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("User deleted"))
+	if userID != int(callerID) && !middleware.HasPermission(c.GetString("role"), middleware.PermUsersManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only change your own account"})
+		return 0, false
+	}
+	return userID, true
 }
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"Delingo/src/events"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"fmt"
+	"log"
+)
+
+// SubscribeEvents sets up the reactions to domain events. Call it once, after
+// the database is initialized.
+func SubscribeEvents() {
+	// Friends see streak milestones in their feed
+	events.Subscribe(events.StreakMilestone, func(e events.Event) {
+		recordActivity(utils.GormDB, e.UserID, models.ActivityStreakMilestone, models.JSONMap{"days": e.Data["days"]})
+	})
+
+	// A streak milestone earns bonus XP, once per milestone
+	events.Subscribe(events.StreakMilestone, func(e events.Event) {
+		days, _ := e.Data["days"].(int)
+		if days <= 0 {
+			return
+		}
+		if _, err := awardXP(e.UserID, models.XPEventBonus, fmt.Sprintf("streak:%d", days), min(days, maxStreakBonusXP)); err != nil {
+			log.Println("Error awarding streak bonus:", err)
+		}
+	})
+
+	// The first XP of a week closes the learner's league table for the last one
+	events.Subscribe(events.XPAwarded, func(e events.Event) {
+		recordLeagueResult(e.UserID, e.At)
+	})
+
+	// Badges whose rules test what changed are checked again
+	for _, name := range []string{events.StreakExtended, events.XPAwarded, events.LessonCompleted} {
+		events.Subscribe(name, func(e events.Event) {
+			evaluateBadges(e.UserID, badgeMetricsFor(e)...)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"Delingo/src/middleware"
 	"Delingo/src/models"
 	"Delingo/src/utils"
 	"log"
@@ -19,8 +20,14 @@ func CreateThread(c *gin.Context) {
 		return
 	}
 
-	// Assuming userID is extracted from the JWT or session
-	thread.UserID = 1 // Replace with actual userID extraction
+	// The author is always the logged-in user
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	thread.ID = 0
+	thread.UserID = userID
 
 	// Insert the thread into the database
 	if err := utils.GormDB.Create(&thread).Error; err != nil {
@@ -28,12 +35,19 @@ func CreateThread(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create thread"})
 		return
 	}
+	recordActivity(utils.GormDB, int(userID), models.ActivityThreadCreated, models.JSONMap{
+		"thread_id": thread.ID,
+		"title":     thread.Title,
+	})
 
 	c.JSON(http.StatusOK, thread)
 }
 
 func GetThread(c *gin.Context) {
-	threadID := c.Param("id")
+	threadID, ok := pathID(c, "thread")
+	if !ok {
+		return
+	}
 	var thread models.Thread
 
 	if err := utils.GormDB.Preload("Posts").First(&thread, threadID).Error; err != nil {
@@ -48,7 +62,11 @@ func GetThread(c *gin.Context) {
 // VoteOnPost allows users to vote on a post
 func VoteOnPost(c *gin.Context) {
 	// Retrieve user ID from JWT
-	userID, _ := c.Get("userID")
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Parse vote value (1 for upvote, -1 for downvote)
 	var input struct {
@@ -69,7 +87,7 @@ func VoteOnPost(c *gin.Context) {
 
 	// Check if the user has already voted on this post
 	var existingVote models.Vote
-	err := utils.GormDB.Where("user_id = ? AND post_id = ?", userID, input.PostID).First(&existingVote).Error
+	err = utils.GormDB.Where("user_id = ? AND post_id = ?", userID, input.PostID).First(&existingVote).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
@@ -93,6 +111,9 @@ func VoteOnPost(c *gin.Context) {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote"})
 				return
 			}
+			if input.VoteValue == 1 {
+				awardUpvoteXP("posts", input.PostID, userID)
+			}
 			c.JSON(http.StatusOK, gin.H{"message": "Vote updated"})
 			return
 		}
@@ -100,7 +121,7 @@ func VoteOnPost(c *gin.Context) {
 
 	// If no existing vote, create a new one
 	newVote := models.Vote{
-		UserID:    userID.(uint),
+		UserID:    userID,
 		PostID:    &input.PostID,
 		VoteValue: input.VoteValue,
 		CreatedAt: time.Now(),
@@ -112,6 +133,9 @@ func VoteOnPost(c *gin.Context) {
 		return
 	}
 
+	if input.VoteValue == 1 {
+		awardUpvoteXP("posts", input.PostID, userID)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Vote created"})
 }
 
@@ -123,6 +147,15 @@ func CreatePost(c *gin.Context) {
 		return
 	}
 
+	// The author is always the logged-in user
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	post.ID = 0
+	post.UserID = userID
+
 	result := utils.GormDB.Create(&post)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
@@ -166,7 +199,10 @@ func GetAllThreads(c *gin.Context) {
 
 // UpdateThread updates an existing thread
 func UpdateThread(c *gin.Context) {
-	threadID := c.Param("id")
+	threadID, ok := pathID(c, "thread")
+	if !ok {
+		return
+	}
 	var thread models.Thread
 
 	// Find the thread by ID
@@ -175,11 +211,21 @@ func UpdateThread(c *gin.Context) {
 		return
 	}
 
+	// Only the author or a moderator may edit
+	if !canModify(c, thread.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own threads"})
+		return
+	}
+
 	// Bind the updated values from the request body
-	if err := c.ShouldBindJSON(&thread); err != nil {
+	var input struct {
+		Title string `json:"title" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	thread.Title = input.Title
 
 	// Update the thread in the database
 	if err := utils.GormDB.Save(&thread).Error; err != nil {
@@ -192,7 +238,10 @@ func UpdateThread(c *gin.Context) {
 
 // DeleteThread deletes a thread from the database
 func DeleteThread(c *gin.Context) {
-	threadID := c.Param("id")
+	threadID, ok := pathID(c, "thread")
+	if !ok {
+		return
+	}
 	var thread models.Thread
 
 	// Find the thread by ID
@@ -201,6 +250,12 @@ func DeleteThread(c *gin.Context) {
 		return
 	}
 
+	// Only the author or a moderator may delete
+	if !canModify(c, thread.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own threads"})
+		return
+	}
+
 	// Delete the thread from the database
 	if err := utils.GormDB.Delete(&thread).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete thread"})
@@ -212,7 +267,10 @@ func DeleteThread(c *gin.Context) {
 
 // UpdatePost updates an existing post
 func UpdatePost(c *gin.Context) {
-	postID := c.Param("id")
+	postID, ok := pathID(c, "post")
+	if !ok {
+		return
+	}
 	var post models.Post
 
 	// Find the post by ID
@@ -221,11 +279,21 @@ func UpdatePost(c *gin.Context) {
 		return
 	}
 
+	// Only the author or a moderator may edit
+	if !canModify(c, post.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own posts"})
+		return
+	}
+
 	// Bind the updated values from the request body
-	if err := c.ShouldBindJSON(&post); err != nil {
+	var input struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	post.Content = input.Content
 
 	// Update the post in the database
 	if err := utils.GormDB.Save(&post).Error; err != nil {
@@ -253,7 +321,10 @@ func GetAllPosts(c *gin.Context) {
 
 // DeletePost deletes a post from the database
 func DeletePost(c *gin.Context) {
-	postID := c.Param("id")
+	postID, ok := pathID(c, "post")
+	if !ok {
+		return
+	}
 	var post models.Post
 
 	// Find the post by ID
@@ -262,6 +333,12 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
+	// Only the author or a moderator may delete
+	if !canModify(c, post.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own posts"})
+		return
+	}
+
 	// Delete the post from the database
 	if err := utils.GormDB.Delete(&post).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
@@ -302,7 +379,15 @@ func CreateComment(c *gin.Context) {
 		return
 	}
 
+	// The author is always the logged-in user
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	comment.ID = 0
 	comment.PostID = int(postID)
+	comment.UserID = int(userID)
 
 	// Save the comment to the database
 	if err := utils.GormDB.Create(&comment).Error; err != nil {
@@ -315,7 +400,10 @@ func CreateComment(c *gin.Context) {
 
 // In controllers/commentController.go
 func UpdateComment(c *gin.Context) {
-	commentID := c.Param("id")
+	commentID, ok := pathID(c, "comment")
+	if !ok {
+		return
+	}
 	var comment models.Comment
 
 	// Find the comment by ID
@@ -324,11 +412,21 @@ func UpdateComment(c *gin.Context) {
 		return
 	}
 
+	// Only the author or a moderator may edit
+	if !canModify(c, uint(comment.UserID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own comments"})
+		return
+	}
+
 	// Bind the updated values
-	if err := c.ShouldBindJSON(&comment); err != nil {
+	var input struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	comment.Content = input.Content
 
 	// Save updated comment
 	if err := utils.GormDB.Save(&comment).Error; err != nil {
@@ -354,7 +452,10 @@ func GetCommentsByPost(c *gin.Context) {
 
 // GetComment retrieves a comment by its ID
 func GetComment(c *gin.Context) {
-	commentID := c.Param("id")
+	commentID, ok := pathID(c, "comment")
+	if !ok {
+		return
+	}
 	var comment models.Comment
 
 	if err := utils.GormDB.First(&comment, commentID).Error; err != nil {
@@ -367,7 +468,10 @@ func GetComment(c *gin.Context) {
 
 // DeleteComment deletes a comment from the database
 func DeleteComment(c *gin.Context) {
-	commentID := c.Param("id")
+	commentID, ok := pathID(c, "comment")
+	if !ok {
+		return
+	}
 	var comment models.Comment
 
 	// Find the comment by ID
@@ -376,6 +480,12 @@ func DeleteComment(c *gin.Context) {
 		return
 	}
 
+	// Only the author or a moderator may delete
+	if !canModify(c, uint(comment.UserID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own comments"})
+		return
+	}
+
 	// Delete the comment from the database
 	if err := utils.GormDB.Delete(&comment).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
@@ -388,7 +498,11 @@ func DeleteComment(c *gin.Context) {
 // VoteOnThread allows users to vote on a thread
 func VoteOnThread(c *gin.Context) {
 	// Retrieve user ID from JWT
-	userID, _ := c.Get("userID")
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Parse vote value (1 for upvote, -1 for downvote)
 	var input struct {
@@ -409,7 +523,7 @@ func VoteOnThread(c *gin.Context) {
 
 	// Check if the user has already voted on this thread
 	var existingVote models.Vote
-	err := utils.GormDB.Where("user_id = ? AND thread_id = ?", userID, input.ThreadID).First(&existingVote).Error
+	err = utils.GormDB.Where("user_id = ? AND thread_id = ?", userID, input.ThreadID).First(&existingVote).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
@@ -433,6 +547,9 @@ func VoteOnThread(c *gin.Context) {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote"})
 				return
 			}
+			if input.VoteValue == 1 {
+				awardUpvoteXP("threads", input.ThreadID, userID)
+			}
 			c.JSON(http.StatusOK, gin.H{"message": "Vote updated"})
 			return
 		}
@@ -440,7 +557,7 @@ func VoteOnThread(c *gin.Context) {
 
 	// If no existing vote, create a new one
 	newVote := models.Vote{
-		UserID:    userID.(uint),
+		UserID:    userID,
 		ThreadID:  &input.ThreadID,
 		VoteValue: input.VoteValue,
 		CreatedAt: time.Now(),
@@ -452,6 +569,9 @@ func VoteOnThread(c *gin.Context) {
 		return
 	}
 
+	if input.VoteValue == 1 {
+		awardUpvoteXP("threads", input.ThreadID, userID)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Vote created"})
 }
 
@@ -475,3 +595,13 @@ func SearchForum(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"threads": threads, "posts": posts})
 }
+
+// canModify reports whether the caller may edit or delete forum content owned by ownerID:
+// authors may change their own content and moderators anyone's
+func canModify(c *gin.Context, ownerID uint) bool {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		return false
+	}
+	return userID == ownerID || middleware.HasPermission(c.GetString("role"), middleware.PermForumModerate)
+}
@@ -0,0 +1,373 @@
+package controllers
+
+import (
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Identity linking failures that are the caller's to fix
+var (
+	errIdentityTaken  = errors.New("This login method is linked to another account")
+	errIdentityLinked = errors.New("This login method is already linked to your account")
+	errEmailLinked    = errors.New("An email is already linked to this account")
+	errLastIdentity   = errors.New("Cannot remove your last login method")
+)
+
+// pendingEmailLink is the SQL condition on user_identities i joined with users
+// u of an email linked to an account registered another way and not yet
+// verified. Until its owner follows the emailed link it can't be used to log
+// in, and signing up with the address drops it.
+const pendingEmailLink = "i.verified_at IS NULL AND u.registration_method <> 'email'"
+
+// emailClaimed reports whether an email is already another account's login,
+// leaving out unproven links
+func emailClaimed(email string) (bool, error) {
+	var claimed bool
+	err := utils.SQLDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_identities i JOIN users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.subject = $2 AND NOT (`+pendingEmailLink+`))`,
+		models.ProviderEmail, email).Scan(&claimed)
+	return claimed, err
+}
+
+// dropPendingEmailLink deletes an unproven link of email to an account other
+// than userID (0 for none) and clears it as that account's email, returning
+// ($1) the email and ($2) userID
+const dropPendingEmailLink = `WITH dropped AS (
+		DELETE FROM user_identities i USING users u
+		WHERE u.id = i.user_id AND i.provider = 'email' AND i.subject = $1 AND i.user_id <> $2 AND ` + pendingEmailLink + `
+		RETURNING i.user_id)
+	UPDATE users SET email = '' WHERE id IN (SELECT user_id FROM dropped) AND email = $1`
+
+// signInWithIdentity logs in the owner of a proven wallet identity, registering
+// a new account the first time the wallet is seen
+func signInWithIdentity(c *gin.Context, provider, subject string) {
+	var user models.User
+	created := false
+
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+		if err == nil {
+			// A signed sign-in proves ownership of an address that was linked without proof
+			if identity.VerifiedAt == nil {
+				if err := tx.Model(&identity).Update("verified_at", time.Now()).Error; err != nil {
+					return err
+				}
+			}
+			return tx.First(&user, identity.UserID).Error
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		user = models.User{RegistrationMethod: provider, Role: models.RoleLearner}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		user.Username = models.DefaultUsername(user.ID)
+		if err := tx.Model(&user).Update("username", user.Username).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		created = true
+		if err := tx.Create(&models.UserIdentity{
+			UserID:     user.ID,
+			Provider:   provider,
+			Subject:    subject,
+			VerifiedAt: &now,
+		}).Error; err != nil {
+			return err
+		}
+		return createProfile(tx, user.ID)
+	})
+	if err != nil {
+		log.Println("Error signing in wallet user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not sign in"})
+		return
+	}
+
+	user.Password = ""
+	if created {
+		writeAuthResponse(c, http.StatusCreated, user)
+		return
+	}
+	writeAuthResponse(c, http.StatusOK, user)
+}
+
+// getUserByIdentity looks up the account linked to the wallet address in the
+// :address route parameter. Wallets are private unless the owner's privacy
+// settings show them to the caller; a hidden wallet is reported as not found.
+func getUserByIdentity(c *gin.Context, provider string) {
+	address := c.Param("address")
+	// Ethereum identities are stored in checksum form
+	if provider == models.ProviderEthereum && common.IsHexAddress(address) {
+		address = common.HexToAddress(address).Hex()
+	}
+
+	var user publicUser
+	err := utils.GormDB.Model(&models.User{}).Select("users.id", "users.username", "users.created_at").
+		Joins("JOIN user_identities ON user_identities.user_id = users.id").
+		Where("user_identities.provider = ? AND user_identities.subject = ? AND user_identities.verified_at IS NOT NULL", provider, address).
+		First(&user).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user"})
+		}
+		return
+	}
+
+	// The route is public; a logged-in caller may be the owner's friend
+	viewerID := 0
+	if id, err := getUserFromToken(c); err == nil {
+		viewerID = int(id)
+	}
+	profile, err := GetUserProfileFromDB(uint(user.ID))
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user"})
+		return
+	}
+	visible, err := profileVisibility(viewerID, user.ID)
+	if err != nil {
+		log.Println("Error checking wallet visibility:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user"})
+		return
+	}
+	// Without a profile there are no settings to show the wallet
+	if profile.ID == 0 || !visible(profile.Privacy.Wallets) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GET /account/identities - List the caller's login methods
+func GetIdentities(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var identities []models.UserIdentity
+	if err := utils.GormDB.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// POST /account/identities/email - Attach an email and password to the caller's account
+func LinkEmailIdentity(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	if email == "" || input.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email and password are required"})
+		return
+	}
+
+	hash, err := utils.HashPassword(input.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	identity := models.UserIdentity{UserID: int(userID), Provider: models.ProviderEmail, Subject: email}
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		// An account has at most one email; it must be unlinked before another is added
+		var count int64
+		if err := tx.Model(&models.UserIdentity{}).
+			Where("user_id = ? AND provider = ?", userID, models.ProviderEmail).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errEmailLinked
+		}
+
+		// Someone else's unproven claim to the address gives way
+		if err := tx.Exec(dropPendingEmailLink, email, userID).Error; err != nil {
+			return err
+		}
+		if err := linkIdentity(tx, &identity); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"email": email, "password": hash}).Error
+	})
+	if err != nil {
+		writeLinkError(c, err)
+		return
+	}
+
+	// The email stays unverified, and can't be used to log in, until the user
+	// follows the emailed link
+	sendVerificationEmail(identity.UserID, identity.Subject)
+
+	c.JSON(http.StatusCreated, identity)
+}
+
+// POST /account/identities/ethereum - Link a wallet proven by a signed SIWE message
+func LinkEthereumIdentity(c *gin.Context) {
+	linkWalletIdentity(c, models.ProviderEthereum, func(message, signature string) (string, error) {
+		address, err := verifyEthereumSignIn(message, signature)
+		return address.Hex(), err
+	})
+}
+
+// POST /account/identities/solana - Link a wallet proven by a signed Solana message
+func LinkSolanaIdentity(c *gin.Context) {
+	linkWalletIdentity(c, models.ProviderSolana, verifySolanaSignIn)
+}
+
+// linkWalletIdentity verifies a signed sign-in message and links its address to the caller
+func linkWalletIdentity(c *gin.Context, provider string, verify func(message, signature string) (string, error)) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		Message   string `json:"message"`
+		Signature string `json:"signature"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	address, err := verify(input.Message, input.Signature)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	identity := models.UserIdentity{UserID: int(userID), Provider: provider, Subject: address, VerifiedAt: &now}
+	if err := linkIdentity(utils.GormDB, &identity); err != nil {
+		writeLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, identity)
+}
+
+// linkIdentity saves identity for its user. An unverified identity left by the
+// old unauthenticated registration is handed over once ownership is proven.
+func linkIdentity(db *gorm.DB, identity *models.UserIdentity) error {
+	var existing models.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return db.Create(identity).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.UserID == identity.UserID {
+		return errIdentityLinked
+	}
+	if existing.VerifiedAt != nil || identity.VerifiedAt == nil {
+		return errIdentityTaken
+	}
+
+	identity.ID = existing.ID
+	return db.Save(identity).Error
+}
+
+// writeLinkError maps an identity linking failure to a response
+func writeLinkError(c *gin.Context, err error) {
+	if err == errIdentityTaken || err == errIdentityLinked || err == errEmailLinked {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	log.Println("Error linking identity:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+}
+
+// DELETE /account/identities/:id - Remove a login method, keeping at least one
+func UnlinkIdentity(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	identityID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity ID"})
+		return
+	}
+
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		// Lock the caller's identities so two concurrent unlinks can't remove them all
+		var identities []models.UserIdentity
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+			return err
+		}
+
+		var target *models.UserIdentity
+		for i := range identities {
+			if uint64(identities[i].ID) == identityID {
+				target = &identities[i]
+			}
+		}
+		if target == nil {
+			return gorm.ErrRecordNotFound
+		}
+		if len(identities) <= 1 {
+			return errLastIdentity
+		}
+
+		if err := tx.Delete(target).Error; err != nil {
+			return err
+		}
+		// Without its email identity the password can no longer be used to log in
+		if target.Provider == models.ProviderEmail {
+			return tx.Model(&models.User{}).Where("id = ?", userID).
+				Updates(map[string]interface{}{"email": "", "password": ""}).Error
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Identity removed"})
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+	case err == errLastIdentity:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Println("Error unlinking identity:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove identity"})
+	}
+}
@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"Delingo/src/events"
+	"Delingo/src/exercises"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errExerciseNotInSession = errors.New("That exercise isn't part of this session")
+	errExerciseAnswered     = errors.New("That exercise has already been answered")
+	errSessionCompleted     = errors.New("This session is already complete")
+)
+
+// lessonSessionView is a session with the exercises to answer and the answers so far
+type lessonSessionView struct {
+	models.LessonSession
+	Exercises []exercises.Served      `json:"exercises"`
+	Answers   []models.ExerciseAnswer `json:"answers"`
+}
+
+// POST /lessons/:id/sessions - Start a lesson the learner has unlocked
+func StartLessonSession(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	lessonID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lesson ID"})
+		return
+	}
+
+	node, err := learnerLesson(int(userID), uint(lessonID))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lesson not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error loading lesson:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start lesson"})
+		return
+	}
+	if node.Locked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This lesson is still locked"})
+		return
+	}
+
+	var list []models.Exercise
+	if err := utils.GormDB.Where("lesson_id = ?", lessonID).Order("position, id").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start lesson"})
+		return
+	}
+	if len(list) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This lesson has no exercises yet"})
+		return
+	}
+
+	session := models.LessonSession{UserID: int(userID), LessonID: uint(lessonID), ExerciseIDs: models.IDList{}}
+	for _, e := range list {
+		session.ExerciseIDs = append(session.ExerciseIDs, e.ID)
+	}
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return startLessonProgress(tx, session.UserID, session.LessonID)
+	})
+	if err != nil {
+		log.Println("Error starting lesson session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start lesson"})
+		return
+	}
+
+	view, err := buildLessonSessionView(session)
+	if err != nil {
+		log.Println("Error serving exercises:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start lesson"})
+		return
+	}
+	c.JSON(http.StatusCreated, view)
+}
+
+// GET /lesson-sessions/:id - Resume a session: its exercises and the answers so far
+func GetLessonSession(c *gin.Context) {
+	session, ok := ownLessonSession(c)
+	if !ok {
+		return
+	}
+	view, err := buildLessonSessionView(session)
+	if err != nil {
+		log.Println("Error serving exercises:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve session"})
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// POST /lesson-sessions/:id/answers - Answer one exercise of a session and get
+// it graded, with the correct solution. Each exercise is answered once; the
+// session completes with its last answer.
+func AnswerExercise(c *gin.Context) {
+	session, ok := ownLessonSession(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ExerciseID uint            `json:"exercise_id" binding:"required"`
+		Answer     json.RawMessage `json:"answer" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exercise_id and answer are required"})
+		return
+	}
+
+	var result exercises.Result
+	var exercise models.Exercise
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		// Lock the session so two answers can't both complete it
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, session.ID).Error; err != nil {
+			return err
+		}
+		if session.CompletedAt != nil {
+			return errSessionCompleted
+		}
+		inSession := false
+		for _, id := range session.ExerciseIDs {
+			inSession = inSession || id == input.ExerciseID
+		}
+		if !inSession {
+			return errExerciseNotInSession
+		}
+
+		if err := tx.First(&exercise, input.ExerciseID).Error; err != nil {
+			return err
+		}
+		var err error
+		if result, err = exercises.Check(exercise, input.Answer); err != nil {
+			return err
+		}
+
+		var answer models.JSONMap
+		if json.Unmarshal(input.Answer, &answer) != nil {
+			return exercises.ErrInvalidAnswer
+		}
+		record := models.ExerciseAnswer{SessionID: session.ID, ExerciseID: exercise.ID, Answer: answer, Correct: result.Correct}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if created.Error != nil {
+			return created.Error
+		}
+		if created.RowsAffected == 0 {
+			return errExerciseAnswered
+		}
+
+		session.Answered++
+		if result.Correct {
+			session.Correct++
+		}
+		// Exercises deleted since the session started can't be answered
+		var remaining int64
+		if err := tx.Model(&models.Exercise{}).Where("id IN ?", []uint(session.ExerciseIDs)).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if int64(session.Answered) >= remaining {
+			now := time.Now()
+			session.CompletedAt = &now
+		}
+		if err := tx.Model(&session).Select("answered", "correct", "completed_at").Updates(&session).Error; err != nil {
+			return err
+		}
+		if session.CompletedAt != nil && !session.Practice {
+			return completeLessonProgress(tx, session)
+		}
+		return nil
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		return
+	case err == exercises.ErrInvalidAnswer || err == errExerciseNotInSession:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err == errExerciseAnswered || err == errSessionCompleted:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Println("Error grading answer:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grade answer"})
+		return
+	}
+
+	reviewAnswer(session.UserID, exercise, result)
+	if session.CompletedAt != nil {
+		awardSessionXP(session)
+		recordPractice(session.UserID)
+		if !session.Practice {
+			recordLessonCompleted(session)
+			events.Publish(events.Event{Name: events.LessonCompleted, UserID: session.UserID, Data: map[string]interface{}{
+				"lesson_id": session.LessonID, "correct": session.Correct, "answered": session.Answered,
+			}})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result":    result,
+		"answered":  session.Answered,
+		"correct":   session.Correct,
+		"total":     len(session.ExerciseIDs),
+		"completed": session.CompletedAt != nil,
+	})
+}
+
+// recordLessonCompleted shares a finished lesson in the learner's activity
+func recordLessonCompleted(session models.LessonSession) {
+	var lesson models.Lesson
+	if err := utils.GormDB.Select("id", "title").First(&lesson, session.LessonID).Error; err != nil {
+		log.Println("Error loading completed lesson:", err)
+		return
+	}
+	recordActivity(utils.GormDB, session.UserID, models.ActivityLessonCompleted, models.JSONMap{
+		"lesson_id": lesson.ID,
+		"title":     lesson.Title,
+		"correct":   session.Correct,
+		"answered":  session.Answered,
+	})
+}
+
+// ownLessonSession loads the :id session, which must belong to the caller. On
+// failure it writes the response and returns false.
+func ownLessonSession(c *gin.Context) (models.LessonSession, bool) {
+	var session models.LessonSession
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return session, false
+	}
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return session, false
+	}
+
+	// Someone else's session is as good as missing
+	err = utils.GormDB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return session, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve session"})
+		return session, false
+	}
+	return session, true
+}
+
+// buildLessonSessionView loads a session's exercises, in the session's order
+// and without their solutions, and its answers
+func buildLessonSessionView(session models.LessonSession) (lessonSessionView, error) {
+	view := lessonSessionView{LessonSession: session, Exercises: []exercises.Served{}, Answers: []models.ExerciseAnswer{}}
+
+	var list []models.Exercise
+	if err := utils.GormDB.Where("id IN ?", []uint(session.ExerciseIDs)).Find(&list).Error; err != nil {
+		return view, err
+	}
+	byID := map[uint]models.Exercise{}
+	for _, e := range list {
+		byID[e.ID] = e
+	}
+	// Exercises deleted since the session started are skipped
+	for _, id := range session.ExerciseIDs {
+		e, ok := byID[id]
+		if !ok {
+			continue
+		}
+		served, err := exercises.Public(e)
+		if err != nil {
+			return view, err
+		}
+		view.Exercises = append(view.Exercises, served)
+	}
+
+	err := utils.GormDB.Where("session_id = ?", session.ID).Order("id").Find(&view.Answers).Error
+	return view, err
+}
+
+// learnerLesson returns a lesson of a published course as userID sees it in
+// the course tree, or gorm.ErrRecordNotFound
+func learnerLesson(userID int, lessonID uint) (lessonNode, error) {
+	var courseID uint
+	err := utils.SQLDB.QueryRow(`
+		SELECT units.course_id FROM lessons
+		JOIN skills ON skills.id = lessons.skill_id
+		JOIN units ON units.id = skills.unit_id
+		WHERE lessons.id = $1`, lessonID).Scan(&courseID)
+	if err == sql.ErrNoRows {
+		return lessonNode{}, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return lessonNode{}, err
+	}
+
+	course, err := loadCourseTree(courseID, false)
+	if err != nil {
+		return lessonNode{}, err
+	}
+	if !course.Published {
+		return lessonNode{}, gorm.ErrRecordNotFound
+	}
+	completed, err := completedLessons(userID, courseID)
+	if err != nil {
+		return lessonNode{}, err
+	}
+
+	for _, unit := range buildCourseTree(course, completed).Units {
+		for _, skill := range unit.Skills {
+			for _, lesson := range skill.Lessons {
+				if lesson.ID == lessonID {
+					return lesson, nil
+				}
+			}
+		}
+	}
+	return lessonNode{}, gorm.ErrRecordNotFound
+}
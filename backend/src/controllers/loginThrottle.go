@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"Delingo/src/config"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// throttleKey names one counter of failed logins
+type throttleKey struct {
+	Scope string
+	Key   string
+}
+
+// loginThrottleKeys returns the counters a login attempt for account is checked against
+func loginThrottleKeys(c *gin.Context, account string) []throttleKey {
+	return []throttleKey{
+		{Scope: models.ThrottleAccount, Key: account},
+		{Scope: models.ThrottleIP, Key: c.ClientIP()},
+	}
+}
+
+// loginLockedFor returns how long until every given counter is unlocked, or 0 when none is locked
+func loginLockedFor(keys []throttleKey) (time.Duration, error) {
+	var wait time.Duration
+	now := time.Now()
+	for _, k := range keys {
+		var throttle models.LoginThrottle
+		result := utils.GormDB.Where("scope = ? AND key = ? AND locked_until > ?", k.Scope, k.Key, now).
+			Limit(1).Find(&throttle)
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected > 0 && throttle.LockedUntil.Sub(now) > wait {
+			wait = throttle.LockedUntil.Sub(now)
+		}
+	}
+	return wait, nil
+}
+
+// recordLoginFailure counts a failed attempt against each counter and locks any
+// that have reached their limit. Counters reset once a whole window passes
+// without failures.
+func recordLoginFailure(c *gin.Context, keys []throttleKey) {
+	now := time.Now()
+	for _, k := range keys {
+		// One atomic upsert so concurrent failures on different replicas are all counted
+		var failures int
+		err := utils.SQLDB.QueryRow(`
+			INSERT INTO login_throttles (scope, key, failures, last_failure_at)
+			VALUES ($1, $2, 1, $3)
+			ON CONFLICT (scope, key) DO UPDATE SET
+				failures = CASE WHEN login_throttles.last_failure_at < $4 THEN 1 ELSE login_throttles.failures + 1 END,
+				last_failure_at = EXCLUDED.last_failure_at
+			RETURNING failures`,
+			k.Scope, k.Key, now, now.Add(-config.LoginFailureWindow)).Scan(&failures)
+		if err != nil {
+			log.Println("Error recording failed login:", err)
+			continue
+		}
+
+		limit := config.LoginMaxAccountFailures
+		if k.Scope == models.ThrottleIP {
+			limit = config.LoginMaxIPFailures
+		}
+		if failures < limit {
+			continue
+		}
+
+		lockedUntil := now.Add(lockoutDuration(failures - limit))
+		err = utils.GormDB.Model(&models.LoginThrottle{}).
+			Where("scope = ? AND key = ?", k.Scope, k.Key).
+			Update("locked_until", lockedUntil).Error
+		if err != nil {
+			log.Println("Error locking login:", err)
+			continue
+		}
+
+		log.Printf("Login locked: %s %q after %d failures, until %s", k.Scope, k.Key, failures, lockedUntil.Format(time.RFC3339))
+		err = utils.GormDB.Create(&models.LockoutEvent{
+			Scope:       k.Scope,
+			Key:         k.Key,
+			IPAddress:   c.ClientIP(),
+			Failures:    failures,
+			LockedUntil: lockedUntil,
+		}).Error
+		if err != nil {
+			log.Println("Error recording lockout:", err)
+		}
+	}
+}
+
+// lockoutDuration doubles the lockout for every failure past the limit, up to the configured maximum
+func lockoutDuration(extraFailures int) time.Duration {
+	d := float64(config.LoginLockoutBase) * math.Pow(2, float64(extraFailures))
+	if d > float64(config.LoginLockoutMax) {
+		return config.LoginLockoutMax
+	}
+	return time.Duration(d)
+}
+
+// clearLoginFailures forgets the failed attempts against an account after it logs in
+func clearLoginFailures(account string) {
+	err := utils.GormDB.Where("scope = ? AND key = ?", models.ThrottleAccount, account).
+		Delete(&models.LoginThrottle{}).Error
+	if err != nil {
+		log.Println("Error clearing failed logins:", err)
+	}
+}
+
+// writeLoginLocked answers an attempt on a locked account or IP
+func writeLoginLocked(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": fmt.Sprintf("Too many failed login attempts. Try again in %s.", humanizeWait(wait)),
+	})
+}
+
+// humanizeWait renders a lockout's remaining time, rounded up to whole minutes
+func humanizeWait(d time.Duration) string {
+	minutes := int(math.Ceil(d.Minutes()))
+	if minutes <= 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pathID reads the :id of the kind of record a request acts on. On failure it
+// writes the response and returns false.
+func pathID(c *gin.Context, kind string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + kind + " ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// exportSection is one part of a personal data export: the rows of a table
+// that belong to the user. Secrets such as password and token hashes are never
+// selected.
+type exportSection struct {
+	Name    string
+	Table   string
+	Columns string
+	Where   string // filter, with the user ID as its only argument
+}
+
+var exportSections = []exportSection{
+	{"account", "users", "id, username, email, registration_method, role, created_at, totp_enabled_at", "id = ?"},
+	{"profile", "profiles", "bio, level, streak, avatar_url, location, website, preferences, social_links, privacy, created_at, updated_at", "user_id = ?"},
+	{"identities", "user_identities", "provider, subject, verified_at, created_at", "user_id = ?"},
+	{"sessions", "sessions", "user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at", "user_id = ?"},
+	{"emails_sent", "user_tokens", "purpose, subject, created_at, expires_at, used_at", "user_id = ?"},
+	{"forum_threads", "threads", "id, title, created_at, updated_at, deleted_at", "user_id = ?"},
+	{"forum_posts", "posts", "id, thread_id, content, created_at, updated_at, deleted_at", "user_id = ?"},
+	{"forum_comments", "comments", "id, post_id, content, created_at, updated_at", "user_id = ?"},
+	{"forum_votes", "votes", "post_id, thread_id, vote_value, created_at, updated_at, deleted_at", "user_id = ?"},
+	{"badges", "user_badges JOIN badges ON badges.id = user_badges.badge_id",
+		"badges.name, badges.description, badges.token_id, user_badges.earned_at", "user_badges.user_id = ?"},
+	{"badge_mints", "badge_mints", "badge_id, contract, address, status, tx_hash, created_at, updated_at", "user_id = ?"},
+	{"following", "follows JOIN users ON users.id = follows.followee_id", "users.username, follows.created_at", "follows.follower_id = ?"},
+	{"followers", "follows JOIN users ON users.id = follows.follower_id", "users.username, follows.created_at", "follows.followee_id = ?"},
+	{"activity", "activities", "kind, data, created_at", "user_id = ?"},
+	{"progress", "progresses", "lesson_id, status, progress, completed_at, updated_at", "user_id = ?"},
+	{"lesson_sessions", "lesson_sessions", "id, lesson_id, practice, answered, correct, created_at, completed_at", "user_id = ?"},
+	{"exercise_answers", "exercise_answers JOIN lesson_sessions ON lesson_sessions.id = exercise_answers.session_id",
+		"exercise_answers.session_id, exercise_answers.exercise_id, exercise_answers.answer, exercise_answers.correct, exercise_answers.created_at",
+		"lesson_sessions.user_id = ?"},
+	{"streak", "streaks", "current, longest, freezes, last_day, timezone, updated_at", "user_id = ?"},
+	{"streak_days", "streak_days", "day, frozen, created_at", "user_id = ?"},
+	{"xp", "xp_entries", "source, ref, amount, day, created_at", "user_id = ?"},
+	{"review_items", "review_items", "language, kind, text, ease, interval_days, repetitions, lapses, due_at, last_reviewed_at, created_at", "user_id = ?"},
+}
+
+// GET /users/:id/export - Download everything held about a user, as a ZIP of
+// JSON files or, with ?format=json, a single JSON document
+func ExportUserData(c *gin.Context) {
+	userID, ok := managedUserID(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be zip or json"})
+		return
+	}
+
+	parts, err := collectUserData(userID)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error exporting user data:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data"})
+		return
+	}
+
+	var body []byte
+	contentType := "application/zip"
+	if format == "json" {
+		contentType = "application/json"
+		document := map[string]interface{}{}
+		for _, part := range parts {
+			document[part.Name] = part.Data
+		}
+		body, err = json.MarshalIndent(document, "", "  ")
+	} else {
+		body, err = zipExport(parts)
+	}
+	if err != nil {
+		log.Println("Error encoding user data export:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data"})
+		return
+	}
+
+	name := fmt.Sprintf("delingo-export-%d-%s.%s", userID, time.Now().UTC().Format("20060102"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// exportPart is a named piece of an export, one file in the ZIP archive
+type exportPart struct {
+	Name string
+	Data interface{}
+}
+
+// zipExport packs each part into a ZIP archive as <name>.json
+func zipExport(parts []exportPart) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, part := range parts {
+		body, err := json.MarshalIndent(part.Data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		w, err := archive.Create(part.Name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// collectUserData gathers every export section for userID and the lockouts of
+// their login emails, after a note on when the export was made
+func collectUserData(userID int) ([]exportPart, error) {
+	var exists bool
+	if err := utils.SQLDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	parts := []exportPart{{Name: "export", Data: gin.H{
+		"user_id":      userID,
+		"generated_at": time.Now().UTC(),
+	}}}
+	for _, section := range exportSections {
+		rows := []map[string]interface{}{}
+		err := utils.GormDB.Table(section.Table).Select(section.Columns).Where(section.Where, userID).Find(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", section.Name, err)
+		}
+		for _, row := range rows {
+			for column, value := range row {
+				row[column] = exportValue(value)
+			}
+		}
+		parts = append(parts, exportPart{Name: section.Name, Data: rows})
+	}
+
+	// Lockouts are keyed by the email typed at login, or the user for 2FA logins
+	keys, err := accountThrottleKeys(utils.GormDB, userID)
+	if err != nil {
+		return nil, err
+	}
+	lockouts := []map[string]interface{}{}
+	err = utils.GormDB.Table("lockout_events").Select("scope, key, ip_address, failures, locked_until, created_at").
+		Where("scope = ? AND key IN ?", models.ThrottleAccount, keys).Find(&lockouts).Error
+	if err != nil {
+		return nil, fmt.Errorf("login_lockouts: %w", err)
+	}
+	parts = append(parts, exportPart{Name: "login_lockouts", Data: lockouts})
+
+	return parts, nil
+}
+
+// exportValue makes a scanned column value readable in JSON. JSONB columns are
+// read as bytes and are embedded as JSON rather than base64.
+func exportValue(value interface{}) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	return string(b)
+}
+
+// accountThrottleKeys returns the account keys failed logins of userID are
+// counted under: each of their email addresses, and the user for 2FA logins
+func accountThrottleKeys(db *gorm.DB, userID int) ([]string, error) {
+	var emails []string
+	err := db.Model(&models.UserIdentity{}).Where("user_id = ? AND provider = ?", userID, models.ProviderEmail).
+		Pluck("subject", &emails).Error
+	if err != nil {
+		return nil, err
+	}
+	var contact string
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Select("COALESCE(email, '')").Scan(&contact).Error; err != nil {
+		return nil, err
+	}
+	if contact != "" {
+		emails = append(emails, strings.ToLower(contact))
+	}
+	return append(emails, fmt.Sprintf("user:%d", userID)), nil
+}
+
+// DELETE /users/:id - Erase a user: remove their personal data, keep their
+// forum content without an author, and leave a tombstone for audit
+func DeleteUser(c *gin.Context) {
+	userID, ok := managedUserID(c)
+	if !ok {
+		return
+	}
+	callerID, _ := getUserFromToken(c)
+
+	// An optional reason, mostly for admins erasing someone else
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+	}
+	if utf8.RuneCountInString(input.Reason) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be at most 500 characters"})
+		return
+	}
+
+	err := eraseUser(userID, int(callerID), input.Reason)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error erasing user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete user"})
+		return
+	}
+	if userID != int(callerID) {
+		recordAudit(c, models.AuditUserErase, userID, models.JSONMap{"reason": input.Reason})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// eraseUser removes everything that identifies userID in one transaction.
+// Threads, posts, comments and votes stay so discussions and scores make sense,
+// but are reassigned to ErasedUserID. Signing out is implied: the sessions go too.
+func eraseUser(userID, erasedBy int, reason string) error {
+	var avatarKeys []string
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		var profile models.Profile
+		if err := tx.Where("user_id = ?", userID).Limit(1).Find(&profile).Error; err != nil {
+			return err
+		}
+		avatarKeys = profile.Avatars.Keys
+
+		keys, err := accountThrottleKeys(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, table := range []string{"threads", "posts", "comments", "votes"} {
+			if err := tx.Exec("UPDATE "+table+" SET user_id = ? WHERE user_id = ?", models.ErasedUserID, userID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM exercise_answers WHERE session_id IN (SELECT id FROM lesson_sessions WHERE user_id = ?)", userID).Error; err != nil {
+			return err
+		}
+		for _, table := range []string{"user_identities", "profiles", "sessions", "user_tokens", "recovery_codes", "user_badges", "activities", "lesson_sessions", "progresses", "review_items", "streaks", "streak_days", "xp_entries", "badge_mints"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE user_id = ?", userID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM follows WHERE follower_id = ? OR followee_id = ?", userID, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope = ? AND key IN ?", models.ThrottleAccount, keys).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope = ? AND key IN ?", models.ThrottleAccount, keys).Delete(&models.LockoutEvent{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.UserTombstone{
+			UserID:             userID,
+			RegistrationMethod: user.RegistrationMethod,
+			RegisteredAt:       user.CreatedAt,
+			ErasedAt:           time.Now(),
+			ErasedBy:           erasedBy,
+			Reason:             reason,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, userID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	removeAvatarFiles(avatarKeys)
+	log.Printf("User %d erased by user %d", userID, erasedBy)
+	return nil
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"Delingo/src/exercises"
+	"Delingo/src/models"
+	"Delingo/src/srs"
+	"Delingo/src/utils"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	practiceSize       = 10  // exercises in a practice session
+	practiceCandidates = 200 // most overdue items a practice session is drawn from
+)
+
+// POST /practice - Start a practice session of exercises for the words and
+// sentences due for review, weak ones most often. ?language= limits it to one
+// language being learned.
+func StartPractice(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	store := srs.Store{DB: utils.GormDB}
+	due, err := store.Due(int(userID), c.Query("language"), now, practiceCandidates)
+	if err != nil {
+		log.Println("Error loading review items:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start practice"})
+		return
+	}
+
+	// Items picked are practised with the exercise they were last met in; an
+	// exercise covering several picked items is served once
+	rng := rand.New(rand.NewSource(now.UnixNano()))
+	session := models.LessonSession{UserID: int(userID), Practice: true, ExerciseIDs: models.IDList{}}
+	seen := map[uint]bool{}
+	for _, i := range srs.Pick(srs.Cards(due), len(due), now, rng) {
+		id := due[i].ExerciseID
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		session.ExerciseIDs = append(session.ExerciseIDs, id)
+		if len(session.ExerciseIDs) == practiceSize {
+			break
+		}
+	}
+	if len(session.ExerciseIDs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Nothing is due for review"})
+		return
+	}
+
+	if err := utils.GormDB.Create(&session).Error; err != nil {
+		log.Println("Error starting practice session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start practice"})
+		return
+	}
+	view, err := buildLessonSessionView(session)
+	if err != nil {
+		log.Println("Error serving exercises:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start practice"})
+		return
+	}
+	c.JSON(http.StatusCreated, view)
+}
+
+// reviewAnswer schedules the next review of what an answered exercise
+// practises, in its course's target language. Failures are logged: the answer
+// itself is already graded and saved.
+func reviewAnswer(userID int, exercise models.Exercise, result exercises.Result) {
+	var language string
+	err := utils.SQLDB.QueryRow(`
+		SELECT courses.target_language FROM lessons
+		JOIN skills ON skills.id = lessons.skill_id
+		JOIN units ON units.id = skills.unit_id
+		JOIN courses ON courses.id = units.course_id
+		WHERE lessons.id = $1`, exercise.LessonID).Scan(&language)
+	if err != nil {
+		log.Println("Error finding exercise language:", err)
+		return
+	}
+
+	items, err := exercises.Items(exercise, language)
+	if err != nil {
+		log.Println("Error reading review items:", err)
+		return
+	}
+	// Feedback on a correct answer means a forgiven typo or accent
+	quality := srs.QualityOf(result.Correct, result.Feedback != "")
+	store := srs.Store{DB: utils.GormDB}
+	if err := store.Record(userID, language, exercise.ID, items, quality, time.Now()); err != nil {
+		log.Println("Error recording review:", err)
+	}
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// courseProgress is how much of a course a learner has finished
+type courseProgress struct {
+	CourseID         uint           `json:"course_id"`
+	Title            string         `json:"title"`
+	SourceLanguage   string         `json:"source_language"`
+	TargetLanguage   string         `json:"target_language"`
+	CompletedLessons int            `json:"completed_lessons"`
+	TotalLessons     int            `json:"total_lessons"`
+	Percent          float64        `json:"percent"`
+	Units            []unitProgress `json:"units,omitempty"`
+}
+
+type unitProgress struct {
+	UnitID           uint            `json:"unit_id"`
+	Title            string          `json:"title"`
+	CompletedLessons int             `json:"completed_lessons"`
+	TotalLessons     int             `json:"total_lessons"`
+	Percent          float64         `json:"percent"`
+	Skills           []skillProgress `json:"skills"`
+}
+
+type skillProgress struct {
+	SkillID          uint             `json:"skill_id"`
+	Title            string           `json:"title"`
+	CompletedLessons int              `json:"completed_lessons"`
+	TotalLessons     int              `json:"total_lessons"`
+	Percent          float64          `json:"percent"`
+	Lessons          []lessonProgress `json:"lessons"`
+}
+
+type lessonProgress struct {
+	LessonID uint    `json:"lesson_id"`
+	Title    string  `json:"title"`
+	Status   string  `json:"status"` // "not-started", or the Progress status
+	Score    float32 `json:"score"`  // best percentage of exercises answered right
+}
+
+// GET /progress - The caller's progress in each course they have started
+func GetProgress(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var courseIDs []uint
+	err = utils.GormDB.Table("progresses").Distinct("units.course_id").
+		Joins("JOIN lessons ON lessons.id = progresses.lesson_id").
+		Joins("JOIN skills ON skills.id = lessons.skill_id").
+		Joins("JOIN units ON units.id = skills.unit_id").
+		Where("progresses.user_id = ?", userID).
+		Pluck("units.course_id", &courseIDs).Error
+	if err != nil {
+		log.Println("Error listing courses in progress:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve progress"})
+		return
+	}
+
+	courses := []courseProgress{}
+	for _, courseID := range courseIDs {
+		progress, err := buildCourseProgress(int(userID), courseID)
+		if err == gorm.ErrRecordNotFound {
+			// Unpublished since the learner started it
+			continue
+		}
+		if err != nil {
+			log.Println("Error computing course progress:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve progress"})
+			return
+		}
+		progress.Units = nil
+		courses = append(courses, progress)
+	}
+	c.JSON(http.StatusOK, courses)
+}
+
+// GET /courses/:id/progress - The caller's progress in a course, by unit, skill and lesson
+func GetCourseProgress(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	courseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	progress, err := buildCourseProgress(int(userID), uint(courseID))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error computing course progress:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve progress"})
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+// buildCourseProgress counts the lessons userID has finished in each skill and
+// unit of a course. A draft course is reported as not found, as GetCourse does.
+func buildCourseProgress(userID int, courseID uint) (courseProgress, error) {
+	course, err := loadCourseTree(courseID, false)
+	if err != nil {
+		return courseProgress{}, err
+	}
+	if !course.Published {
+		return courseProgress{}, gorm.ErrRecordNotFound
+	}
+	records, err := lessonProgressRecords(userID, courseID)
+	if err != nil {
+		return courseProgress{}, err
+	}
+
+	result := courseProgress{
+		CourseID:       course.ID,
+		Title:          course.Title,
+		SourceLanguage: course.SourceLanguage,
+		TargetLanguage: course.TargetLanguage,
+		Units:          []unitProgress{},
+	}
+	for _, unit := range course.Units {
+		up := unitProgress{UnitID: unit.ID, Title: unit.Title, Skills: []skillProgress{}}
+		for _, skill := range unit.Skills {
+			sp := skillProgress{SkillID: skill.ID, Title: skill.Title, Lessons: []lessonProgress{}}
+			for _, lesson := range skill.Lessons {
+				lp := lessonProgress{LessonID: lesson.ID, Title: lesson.Title, Status: "not-started"}
+				if record, ok := records[lesson.ID]; ok {
+					lp.Status, lp.Score = record.Status, record.Progress
+				}
+				if lp.Status == models.ProgressCompleted {
+					sp.CompletedLessons++
+				}
+				sp.TotalLessons++
+				sp.Lessons = append(sp.Lessons, lp)
+			}
+			sp.Percent = percent(sp.CompletedLessons, sp.TotalLessons)
+			up.CompletedLessons += sp.CompletedLessons
+			up.TotalLessons += sp.TotalLessons
+			up.Skills = append(up.Skills, sp)
+		}
+		up.Percent = percent(up.CompletedLessons, up.TotalLessons)
+		result.CompletedLessons += up.CompletedLessons
+		result.TotalLessons += up.TotalLessons
+		result.Units = append(result.Units, up)
+	}
+	result.Percent = percent(result.CompletedLessons, result.TotalLessons)
+	return result, nil
+}
+
+// percent is done out of total as a percentage to one decimal place
+func percent(done, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(done)*1000/float64(total)) / 10
+}
+
+// lessonProgressRecords returns userID's progress in each lesson of a course
+// they have started
+func lessonProgressRecords(userID int, courseID uint) (map[uint]models.Progress, error) {
+	var records []models.Progress
+	err := utils.GormDB.Table("progresses").Select("progresses.*").
+		Joins("JOIN lessons ON lessons.id = progresses.lesson_id").
+		Joins("JOIN skills ON skills.id = lessons.skill_id").
+		Joins("JOIN units ON units.id = skills.unit_id").
+		Where("progresses.user_id = ? AND units.course_id = ?", userID, courseID).
+		Scan(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	byLesson := map[uint]models.Progress{}
+	for _, r := range records {
+		byLesson[r.LessonID] = r
+	}
+	return byLesson, nil
+}
+
+// completedLessons returns the lessons of a course userID has finished
+func completedLessons(userID int, courseID uint) (map[uint]bool, error) {
+	records, err := lessonProgressRecords(userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	completed := map[uint]bool{}
+	for id, r := range records {
+		completed[id] = r.Status == models.ProgressCompleted
+	}
+	return completed, nil
+}
+
+// startLessonProgress marks a lesson as begun, unless the learner has already
+// started or finished it before
+func startLessonProgress(db *gorm.DB, userID int, lessonID uint) error {
+	progress := models.Progress{UserID: userID, LessonID: lessonID, Status: models.ProgressInProgress}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "lesson_id"}},
+		DoNothing: true,
+	}).Create(&progress).Error
+}
+
+// completeLessonProgress records a finished session of a lesson: the lesson is
+// completed and its best score kept
+func completeLessonProgress(db *gorm.DB, session models.LessonSession) error {
+	now := time.Now()
+	score := float32(0)
+	if session.Answered > 0 {
+		score = float32(math.Round(float64(session.Correct)*1000/float64(session.Answered)) / 10)
+	}
+
+	progress := models.Progress{
+		UserID:      session.UserID,
+		LessonID:    session.LessonID,
+		Status:      models.ProgressCompleted,
+		Progress:    score,
+		CompletedAt: &now,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "lesson_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "status"}, Value: models.ProgressCompleted},
+			{Column: clause.Column{Name: "progress"}, Value: gorm.Expr("GREATEST(progresses.progress, EXCLUDED.progress)")},
+			{Column: clause.Column{Name: "completed_at"}, Value: gorm.Expr("COALESCE(progresses.completed_at, EXCLUDED.completed_at)")},
+			{Column: clause.Column{Name: "updated_at"}, Value: now},
+		},
+	}).Create(&progress).Error
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// publicProfile is a user's profile as someone else sees it. Parts the owner's
+// privacy settings hide from the viewer are left out.
+type publicProfile struct {
+	Username    string              `json:"username"`
+	AvatarURL   string              `json:"avatar_url"`
+	Avatars     models.Avatars      `json:"avatars"`
+	MemberSince time.Time           `json:"member_since"`
+	Bio         *string             `json:"bio,omitempty"`
+	Location    *string             `json:"location,omitempty"`
+	Website     *string             `json:"website,omitempty"`
+	SocialLinks *models.SocialLinks `json:"social_links,omitempty"`
+	Level       *int                `json:"level,omitempty"`
+	Streak      *int                `json:"streak,omitempty"`
+	Badges      []publicBadge       `json:"badges,omitempty"`
+	Reputation  *int                `json:"reputation,omitempty"`
+	Wallets     []publicWallet      `json:"wallets,omitempty"`
+}
+
+type publicBadge struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	TokenID     uint64    `json:"token_id"`
+	EarnedAt    time.Time `json:"earned_at"`
+}
+
+// publicWallet is a verified wallet address, without any of the identity's bookkeeping
+type publicWallet struct {
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+}
+
+// GET /profiles/:username - A user's public profile, as far as their privacy settings allow
+func GetPublicProfile(c *gin.Context) {
+	var user models.User
+	err := utils.GormDB.Select("id", "username", "created_at").
+		Where("lower(username) = lower(?)", c.Param("username")).First(&user).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error finding user by username:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+
+	profile, err := GetUserProfileFromDB(uint(user.ID))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+
+	// The route is public; a logged-in viewer may see more
+	viewerID := 0
+	if id, err := getUserFromToken(c); err == nil {
+		viewerID = int(id)
+	}
+	visible, err := profileVisibility(viewerID, user.ID)
+	if err != nil {
+		log.Println("Error checking profile visibility:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+
+	result, err := buildPublicProfile(user, profile, visible)
+	if err != nil {
+		log.Println("Error building public profile:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// profileVisibility returns a check of whether viewerID (0 when anonymous) may
+// see the parts of ownerID's profile with a given visibility
+func profileVisibility(viewerID, ownerID int) (func(visibility string) bool, error) {
+	if viewerID != 0 && viewerID == ownerID {
+		return func(string) bool { return true }, nil
+	}
+
+	friends := false
+	if viewerID != 0 {
+		var err error
+		if friends, err = areFriends(viewerID, ownerID); err != nil {
+			return nil, err
+		}
+	}
+	return func(visibility string) bool {
+		return visibility == models.VisibilityPublic || (visibility == models.VisibilityFriends && friends)
+	}, nil
+}
+
+// buildPublicProfile assembles the parts of a profile the viewer may see
+func buildPublicProfile(user models.User, profile models.Profile, visible func(string) bool) (publicProfile, error) {
+	privacy := profile.Privacy
+	result := publicProfile{
+		Username:    user.Username,
+		AvatarURL:   profile.AvatarURL,
+		Avatars:     profile.Avatars,
+		MemberSince: user.CreatedAt,
+	}
+
+	if visible(privacy.About) {
+		result.Bio = &profile.Bio
+		result.Location = &profile.Location
+		result.Website = &profile.Website
+		result.SocialLinks = &profile.SocialLinks
+	}
+	if visible(privacy.Level) {
+		result.Level = &profile.Level
+	}
+	if visible(privacy.Streak) {
+		streak, err := currentStreak(user.ID)
+		if err != nil {
+			return publicProfile{}, err
+		}
+		result.Streak = &streak
+	}
+
+	if visible(privacy.Badges) {
+		var earned []models.UserBadge
+		if err := utils.GormDB.Preload("Badge").Where("user_id = ?", user.ID).Order("earned_at").Find(&earned).Error; err != nil {
+			return publicProfile{}, err
+		}
+		for _, e := range earned {
+			result.Badges = append(result.Badges, publicBadge{
+				Name:        e.Badge.Name,
+				Description: e.Badge.Description,
+				TokenID:     e.Badge.TokenID,
+				EarnedAt:    e.EarnedAt,
+			})
+		}
+	}
+
+	if visible(privacy.Reputation) {
+		reputation, err := forumReputation(user.ID)
+		if err != nil {
+			return publicProfile{}, err
+		}
+		result.Reputation = &reputation
+	}
+
+	if visible(privacy.Wallets) {
+		// Only addresses the user has proven they own
+		var identities []models.UserIdentity
+		err := utils.GormDB.Where("user_id = ? AND provider IN ? AND verified_at IS NOT NULL",
+			user.ID, []string{models.ProviderEthereum, models.ProviderSolana}).
+			Order("created_at").Find(&identities).Error
+		if err != nil {
+			return publicProfile{}, err
+		}
+		for _, identity := range identities {
+			result.Wallets = append(result.Wallets, publicWallet{Chain: identity.Provider, Address: identity.Subject})
+		}
+	}
+
+	return result, nil
+}
+
+// forumReputation is the net score of the votes others have cast on a user's
+// threads and posts
+func forumReputation(userID int) (int, error) {
+	var reputation int
+	err := utils.SQLDB.QueryRow(`
+		SELECT COALESCE(SUM(v.vote_value), 0)
+		FROM votes v
+		LEFT JOIN posts p ON p.id = v.post_id AND p.deleted_at IS NULL
+		LEFT JOIN threads t ON t.id = v.thread_id AND t.deleted_at IS NULL
+		WHERE v.deleted_at IS NULL AND v.user_id <> $1 AND (p.user_id = $1 OR t.user_id = $1)`,
+		userID).Scan(&reputation)
+	return reputation, err
+}
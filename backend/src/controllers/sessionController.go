@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"Delingo/src/config"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errRefreshTokenInvalid is returned for unknown, revoked, expired or replayed refresh tokens
+var errRefreshTokenInvalid = errors.New("Invalid or expired refresh token")
+
+// tokenPair is the access and refresh token handed out when a session starts or is refreshed
+type tokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+}
+
+// startSession records a new session for user and issues its first token pair
+func startSession(c *gin.Context, user models.User) (tokenPair, error) {
+	refreshToken, refreshHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        c.Request.UserAgent(),
+		IPAddress:        c.ClientIP(),
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(config.RefreshTokenTTL),
+	}
+	if err := utils.GormDB.Create(&session).Error; err != nil {
+		return tokenPair{}, err
+	}
+
+	return newTokenPair(user.ID, session.ID, user.Role, refreshToken)
+}
+
+// newTokenPair signs an access token for a session and pairs it with its refresh token
+func newTokenPair(userID int, sessionID uint, role, refreshToken string) (tokenPair, error) {
+	accessToken, err := utils.GenerateToken(userID, sessionID, role)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	return tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// rotateRefreshToken swaps a refresh token for a new pair. Presenting a token
+// that was already rotated out means it leaked, so the whole session is revoked.
+func rotateRefreshToken(c *gin.Context, refreshToken string) (tokenPair, error) {
+	oldHash := utils.HashToken(refreshToken)
+	now := time.Now()
+
+	var session models.Session
+	err := utils.GormDB.Where("refresh_token_hash = ?", oldHash).First(&session).Error
+	if err == gorm.ErrRecordNotFound {
+		// Reuse of a rotated token: kill the session it belonged to
+		result := utils.GormDB.Model(&models.Session{}).
+			Where("previous_token_hash = ? AND revoked_at IS NULL", oldHash).
+			Update("revoked_at", now)
+		if result.Error == nil && result.RowsAffected > 0 {
+			log.Println("Refresh token reuse detected; session revoked")
+		}
+		return tokenPair{}, errRefreshTokenInvalid
+	}
+	if err != nil {
+		return tokenPair{}, err
+	}
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return tokenPair{}, errRefreshTokenInvalid
+	}
+
+	newToken, newHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	// Conditional on the old hash so two concurrent refreshes can't both succeed
+	result := utils.GormDB.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", session.ID, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  newHash,
+			"previous_token_hash": oldHash,
+			"user_agent":          c.Request.UserAgent(),
+			"ip_address":          c.ClientIP(),
+			"last_seen_at":        now,
+			"expires_at":          now.Add(config.RefreshTokenTTL),
+		})
+	if result.Error != nil {
+		return tokenPair{}, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return tokenPair{}, errRefreshTokenInvalid
+	}
+
+	// Pick up role changes made since the last refresh
+	var user models.User
+	if err := utils.GormDB.Select("id", "role").First(&user, session.UserID).Error; err != nil {
+		return tokenPair{}, err
+	}
+
+	return newTokenPair(session.UserID, session.ID, user.Role, newToken)
+}
+
+// revokeUserSessions revokes every active session of a user except keepID (0 keeps none)
+func revokeUserSessions(db *gorm.DB, userID int, keepID uint) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// POST /refresh - Exchange a refresh token for a new access and refresh token
+func RefreshSession(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	tokens, err := rotateRefreshToken(c, input.RefreshToken)
+	if err == errRefreshTokenInvalid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error refreshing session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// POST /logout - Revoke the session that owns the given refresh token
+func Logout(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	err := utils.GormDB.Model(&models.Session{}).
+		Where("refresh_token_hash = ? AND revoked_at IS NULL", utils.HashToken(input.RefreshToken)).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not log out"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GET /sessions - List the caller's active sessions
+func GetSessions(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var sessions []models.Session
+	err = utils.GormDB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sessions"})
+		return
+	}
+
+	currentID := c.GetUint("sessionID")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// DELETE /sessions/:id - Revoke one of the caller's sessions
+func RevokeSession(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	sessionID, ok := pathID(c, "session")
+	if !ok {
+		return
+	}
+
+	result := utils.GormDB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// DELETE /sessions - Revoke every session except the one making the request
+func RevokeOtherSessions(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := revokeUserSessions(utils.GormDB, int(userID), c.GetUint("sessionID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked"})
+}
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Page sizes for lists paged with ?cursor=&limit=
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// POST /users/:id/follow - Follow a user
+func FollowUser(c *gin.Context) {
+	userID, targetID, ok := followTarget(c)
+	if !ok {
+		return
+	}
+
+	var exists bool
+	if err := utils.SQLDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, targetID).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow user"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Following twice is harmless
+	follow := models.Follow{FollowerID: userID, FolloweeID: targetID}
+	if err := utils.GormDB.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		log.Println("Error following user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow user"})
+		return
+	}
+
+	writeFollowStatus(c, userID, targetID)
+}
+
+// DELETE /users/:id/follow - Stop following a user
+func UnfollowUser(c *gin.Context) {
+	userID, targetID, ok := followTarget(c)
+	if !ok {
+		return
+	}
+
+	err := utils.GormDB.Where("follower_id = ? AND followee_id = ?", userID, targetID).Delete(&models.Follow{}).Error
+	if err != nil {
+		log.Println("Error unfollowing user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow user"})
+		return
+	}
+
+	writeFollowStatus(c, userID, targetID)
+}
+
+// followTarget reads the caller and the :id user they want to (un)follow. On
+// failure it writes the response and returns false.
+func followTarget(c *gin.Context) (int, int, bool) {
+	callerID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, 0, false
+	}
+	if targetID == int(callerID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't follow yourself"})
+		return 0, 0, false
+	}
+	return int(callerID), targetID, true
+}
+
+// writeFollowStatus answers a follow change with how the two users now relate
+func writeFollowStatus(c *gin.Context, userID, targetID int) {
+	var following, followedBy bool
+	err := utils.SQLDB.QueryRow(`SELECT
+			EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2),
+			EXISTS (SELECT 1 FROM follows WHERE follower_id = $2 AND followee_id = $1)`,
+		userID, targetID).Scan(&following, &followedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check follow status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"following":   following,
+		"followed_by": followedBy,
+		"friends":     following && followedBy,
+	})
+}
+
+// followEntry is a user in a followers, following or friends list
+type followEntry struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	Since     time.Time `json:"since"` // when they started following, or became friends
+	FollowID  uint      `json:"-"`     // for paging
+}
+
+// GET /users/:id/followers - Users following :id, newest first
+func GetFollowers(c *gin.Context) {
+	listFollows(c, "followee_id", "follower_id")
+}
+
+// GET /users/:id/following - Users :id follows, newest first
+func GetFollowing(c *gin.Context) {
+	listFollows(c, "follower_id", "followee_id")
+}
+
+// listFollows pages through the follows whose match column is :id, listing the
+// users in the other column
+func listFollows(c *gin.Context, match, other string) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	cursor, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	query := utils.GormDB.Table("follows").
+		Select("follows.id AS follow_id, users.id, users.username, users.created_at, follows.created_at AS since").
+		Joins("JOIN users ON users.id = follows."+other).
+		Where("follows."+match+" = ?", userID).
+		Order("follows.id DESC").Limit(limit + 1)
+	if cursor > 0 {
+		query = query.Where("follows.id < ?", cursor)
+	}
+
+	var entries []followEntry
+	if err := query.Scan(&entries).Error; err != nil {
+		log.Println("Error listing follows:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	var next *string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		next = cursorString(entries[limit-1].FollowID)
+	}
+	if entries == nil {
+		entries = []followEntry{}
+	}
+	c.JSON(http.StatusOK, gin.H{"users": entries, "next_cursor": next})
+}
+
+// GET /friends - The caller's friends, the users they follow who follow them back
+func GetFriends(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var friends []followEntry
+	err = utils.GormDB.Table("follows f").
+		Select("users.id, users.username, users.created_at, GREATEST(f.created_at, b.created_at) AS since").
+		Joins("JOIN follows b ON b.follower_id = f.followee_id AND b.followee_id = f.follower_id").
+		Joins("JOIN users ON users.id = f.followee_id").
+		Where("f.follower_id = ?", userID).
+		Order("users.username").
+		Scan(&friends).Error
+	if err != nil {
+		log.Println("Error listing friends:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list friends"})
+		return
+	}
+	if friends == nil {
+		friends = []followEntry{}
+	}
+	c.JSON(http.StatusOK, friends)
+}
+
+// areFriends reports whether two users follow each other
+func areFriends(userID, otherID int) (bool, error) {
+	var friends bool
+	err := utils.SQLDB.QueryRow(`SELECT
+			EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2) AND
+			EXISTS (SELECT 1 FROM follows WHERE follower_id = $2 AND followee_id = $1)`,
+		userID, otherID).Scan(&friends)
+	return friends, err
+}
+
+// feedItem is an activity in the feed, with who did it
+type feedItem struct {
+	models.Activity
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// GET /feed - What the caller's friends have been doing, newest first
+func GetFeed(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	cursor, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	// Friends are followees who follow back; anyone who made their activity private is left out
+	friends := utils.GormDB.Table("follows f").Select("f.followee_id").
+		Joins("JOIN follows b ON b.follower_id = f.followee_id AND b.followee_id = f.follower_id").
+		Where("f.follower_id = ?", userID)
+
+	query := utils.GormDB.Table("activities").
+		Select("activities.*, users.username, profiles.avatar_url").
+		Joins("JOIN users ON users.id = activities.user_id").
+		Joins("LEFT JOIN profiles ON profiles.user_id = activities.user_id").
+		Where("activities.user_id IN (?)", friends).
+		Where("COALESCE(profiles.privacy->>'activity', ?) <> ?", models.VisibilityFriends, models.VisibilityPrivate).
+		Order("activities.id DESC").Limit(limit + 1)
+	if cursor > 0 {
+		query = query.Where("activities.id < ?", cursor)
+	}
+
+	var items []feedItem
+	if err := query.Scan(&items).Error; err != nil {
+		log.Println("Error loading feed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+
+	var next *string
+	if len(items) > limit {
+		items = items[:limit]
+		next = cursorString(items[limit-1].ID)
+	}
+	if items == nil {
+		items = []feedItem{}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "next_cursor": next})
+}
+
+// recordActivity adds an entry to userID's activity for their friends' feeds.
+// The activity is a side effect, so failures are logged rather than returned.
+func recordActivity(db *gorm.DB, userID int, kind string, data models.JSONMap) {
+	activity := models.Activity{UserID: userID, Kind: kind, Data: data}
+	if err := db.Create(&activity).Error; err != nil {
+		log.Println("Error recording activity:", err)
+	}
+}
+
+// pageParams reads the ?cursor= and ?limit= of a keyset-paged list. The cursor
+// is the next_cursor of the previous page. On failure it writes the response
+// and returns false.
+func pageParams(c *gin.Context) (uint, int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit < 1 || limit > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize)})
+		return 0, 0, false
+	}
+
+	var cursor uint64
+	if raw := c.Query("cursor"); raw != "" {
+		if cursor, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return 0, 0, false
+		}
+	}
+	return uint(cursor), limit, true
+}
+
+// cursorString renders an ID as the cursor for the page after it
+func cursorString(id uint) *string {
+	s := strconv.FormatUint(uint64(id), 10)
+	return &s
+}
@@ -3,68 +3,67 @@ package controllers
 import (
 	"Delingo/src/models"
 	"Delingo/src/utils"
-	"database/sql"
-	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
-	"github.com/gorilla/mux"
+	"github.com/gin-gonic/gin"
 )
 
-// RegisterSolanaUser registers a user using a Solana wallet
-func RegisterSolanaUser(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
-	}
+// GET /solana/nonce - Issue a nonce for a Solana sign-in message
+func GetSolanaNonce(c *gin.Context) {
+	issueSignInNonce(c, models.ProviderSolana)
+}
 
-	// Ensure the user has a valid Solana wallet address
-	if user.SolanaWalletAddr == "" {
-		http.Error(w, "Solana wallet address required", http.StatusBadRequest)
+// POST /solana/verify - Log in a Solana wallet user from a signed sign-in message,
+// creating the account on first sign-in
+func VerifySolanaSignIn(c *gin.Context) {
+	var input struct {
+		Message   string `json:"message"`
+		Signature string `json:"signature"` // base58-encoded ed25519 signature
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	// Clear other fields not needed for wallet-based sign-up
-	user.Email = ""
-	user.Password = ""
-
-	// Save user to the database
-	query := `INSERT INTO users (username, email, wallet_address, password, created_at) VALUES ($1, $2, $3, $4, NOW()) RETURNING id`
-	err = utils.SQLDB.QueryRow(query, user.Username, user.Email, user.SolanaWalletAddr, user.Password).Scan(&user.ID)
+	address, err := verifySolanaSignIn(input.Message, input.Signature)
 	if err != nil {
-		http.Error(w, "Could not create user", http.StatusInternalServerError)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Respond with user details
-	json.NewEncoder(w).Encode(user)
+	signInWithIdentity(c, models.ProviderSolana, address)
 }
 
-// GetSolanaUser retrieves a user by their Solana wallet address
-func GetSolanaUser(w http.ResponseWriter, r *http.Request) {
-	// Extract wallet address from URL params
-	walletAddr := mux.Vars(r)["walletAddr"]
+// verifySolanaSignIn checks a Solana sign-in message and signature and returns the proven address
+func verifySolanaSignIn(message, signature string) (string, error) {
+	msg, err := utils.ParseSignInMessage(message)
+	if err != nil {
+		return "", err
+	}
+	if err := msg.Validate(models.ProviderSolana, time.Now()); err != nil {
+		return "", err
+	}
 
-	// Ensure the wallet address is provided
-	if walletAddr == "" {
-		http.Error(w, "Solana wallet address required", http.StatusBadRequest)
-		return
+	// The address in the message is the public key the signature must verify against
+	if err := utils.VerifySolanaSignature(message, signature, msg.Address); err != nil {
+		return "", err
 	}
 
-	// Query the database for a user with the provided wallet address
-	var user models.User
-	query := `SELECT id, username, email, wallet_address, created_at FROM users WHERE wallet_address = $1`
-	err := utils.SQLDB.QueryRow(query, walletAddr).Scan(&user.ID, &user.Username, &user.Email, &user.SolanaWalletAddr, &user.CreatedAt)
+	// Only burn the nonce once everything else checks out
+	ok, err := consumeSignInNonce(msg.Nonce, models.ProviderSolana)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Error retrieving user", http.StatusInternalServerError)
-		}
-		return
+		return "", errors.New("could not check nonce")
 	}
+	if !ok {
+		return "", errors.New("nonce is invalid or expired")
+	}
+
+	return msg.Address, nil
+}
 
-	// Respond with user details
-	json.NewEncoder(w).Encode(user)
+// GET /solana/address/:address - Retrieve a user by a linked Solana wallet address
+func GetSolanaUser(c *gin.Context) {
+	getUserByIdentity(c, models.ProviderSolana)
 }
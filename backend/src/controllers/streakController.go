@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"Delingo/src/events"
+	"Delingo/src/models"
+	"Delingo/src/streaks"
+	"Delingo/src/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// streakView is a learner's streak as of now
+type streakView struct {
+	Current        int    `json:"current"`
+	Longest        int    `json:"longest"`
+	Freezes        int    `json:"freezes"`
+	MaxFreezes     int    `json:"max_freezes"`
+	PractisedToday bool   `json:"practised_today"`
+	Today          string `json:"today"` // in the learner's time zone
+	Timezone       string `json:"timezone"`
+	NextMilestone  int    `json:"next_milestone,omitempty"`
+}
+
+// GET /streak - The caller's streak, with missed days settled: covered by
+// freezes or, without enough, ending the streak
+func GetStreak(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	outcome, loc, err := updateStreak(int(userID), now, false)
+	if err != nil {
+		log.Println("Error settling streak:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve streak"})
+		return
+	}
+
+	s := outcome.State
+	today := streaks.Day(now, loc)
+	c.JSON(http.StatusOK, streakView{
+		Current:        s.Current,
+		Longest:        s.Longest,
+		Freezes:        s.Freezes,
+		MaxFreezes:     streaks.MaxFreezes,
+		PractisedToday: s.Current > 0 && streaks.DaysBetween(s.LastDay, today) <= 0,
+		Today:          today,
+		Timezone:       loc.String(),
+		NextMilestone:  streaks.NextMilestone(s.Current),
+	})
+}
+
+// GET /streak/calendar - The caller's practised and frozen days in a month,
+// ?month=YYYY-MM, by default the current one in their time zone
+func GetStreakCalendar(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	month := c.Query("month")
+	if month == "" {
+		loc, err := learnerLocation(utils.GormDB, int(userID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve streak calendar"})
+			return
+		}
+		month = time.Now().In(loc).Format("2006-01")
+	}
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+		return
+	}
+
+	days := []models.StreakDay{}
+	err = utils.GormDB.Where("user_id = ? AND day >= ? AND day < ?",
+		userID, start.Format("2006-01-02"), start.AddDate(0, 1, 0).Format("2006-01-02")).
+		Order("day").Find(&days).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve streak calendar"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"month": month, "days": days})
+}
+
+// recordPractice counts a finished session towards the learner's streak.
+// Failures are logged: the session itself is already saved.
+func recordPractice(userID int) {
+	if _, _, err := updateStreak(userID, time.Now(), true); err != nil {
+		log.Println("Error updating streak:", err)
+	}
+}
+
+// updateStreak settles a learner's streak at now and, if practised, counts
+// the day. The streak, its history and the profile's streak are saved
+// together; the events follow once they are committed.
+func updateStreak(userID int, now time.Time, practised bool) (streaks.Outcome, *time.Location, error) {
+	var outcome streaks.Outcome
+	var loc *time.Location
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if loc, err = learnerLocation(tx, userID); err != nil {
+			return err
+		}
+
+		// Lock the streak so two sessions finishing at once count once
+		streak := models.Streak{UserID: userID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&streak).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&streak, "user_id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		state := streakState(streak)
+		if practised {
+			outcome = streaks.Practise(state, now, loc)
+		} else {
+			outcome = streaks.Check(state, now, loc)
+		}
+		if outcome.State == state {
+			return nil
+		}
+
+		s := outcome.State
+		err = tx.Model(&streak).Updates(map[string]interface{}{
+			"current":    s.Current,
+			"longest":    s.Longest,
+			"freezes":    s.Freezes,
+			"last_day":   s.LastDay,
+			"timezone":   s.Timezone,
+			"updated_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Profile{}).Where("user_id = ?", userID).Update("streak", s.Current).Error; err != nil {
+			return err
+		}
+
+		var days []models.StreakDay
+		for _, day := range outcome.Frozen {
+			days = append(days, models.StreakDay{UserID: userID, Day: day, Frozen: true})
+		}
+		if outcome.Extended {
+			days = append(days, models.StreakDay{UserID: userID, Day: s.LastDay})
+		}
+		if len(days) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&days).Error
+	})
+	if err != nil {
+		return outcome, loc, err
+	}
+
+	publishStreakEvents(userID, outcome, now)
+	return outcome, loc, nil
+}
+
+// publishStreakEvents announces what happened to a streak
+func publishStreakEvents(userID int, outcome streaks.Outcome, now time.Time) {
+	if len(outcome.Frozen) > 0 {
+		events.Publish(events.Event{Name: events.StreakFrozen, UserID: userID, At: now, Data: map[string]interface{}{"days": outcome.Frozen}})
+	}
+	if outcome.Lost > 0 {
+		events.Publish(events.Event{Name: events.StreakLost, UserID: userID, At: now, Data: map[string]interface{}{"length": outcome.Lost}})
+	}
+	if outcome.Extended {
+		events.Publish(events.Event{Name: events.StreakExtended, UserID: userID, At: now, Data: map[string]interface{}{"current": outcome.State.Current}})
+	}
+	if outcome.Milestone > 0 {
+		events.Publish(events.Event{Name: events.StreakMilestone, UserID: userID, At: now, Data: map[string]interface{}{"days": outcome.Milestone}})
+	}
+}
+
+// learnerLocation returns the time zone of a learner's preferences, or UTC if
+// it can't be loaded
+func learnerLocation(db *gorm.DB, userID int) (*time.Location, error) {
+	prefs, err := learnerPreferences(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return preferredLocation(prefs), nil
+}
+
+// currentStreak returns a learner's streak as it stands now, without saving
+// anything: a streak that missed days have broken shows as 0 even before it
+// is next settled
+func currentStreak(userID int) (int, error) {
+	var streak models.Streak
+	if err := utils.GormDB.Where("user_id = ?", userID).Limit(1).Find(&streak).Error; err != nil {
+		return 0, err
+	}
+	loc, err := learnerLocation(utils.GormDB, userID)
+	if err != nil {
+		return 0, err
+	}
+	return streaks.Check(streakState(streak), time.Now(), loc).State.Current, nil
+}
+
+// streakState reads a stored streak
+func streakState(streak models.Streak) streaks.State {
+	return streaks.State{
+		Current:  streak.Current,
+		Longest:  streak.Longest,
+		Freezes:  streak.Freezes,
+		LastDay:  streak.LastDay,
+		Timezone: streak.Timezone,
+	}
+}
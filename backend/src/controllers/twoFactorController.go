@@ -0,0 +1,471 @@
+package controllers
+
+import (
+	"Delingo/src/config"
+	"Delingo/src/middleware"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is how many recovery codes are issued at a time
+const recoveryCodeCount = 10
+
+// Second factor failures that are the caller's to fix
+var (
+	errTwoFactorCodeRequired = errors.New("A TOTP code or recovery code is required")
+	errTwoFactorCodeInvalid  = errors.New("Invalid or already used code")
+	errTwoFactorEnabled      = errors.New("Two-factor authentication is already enabled")
+	errTwoFactorNotSetUp     = errors.New("Two-factor authentication is not set up")
+	errTwoFactorMandatory    = errors.New("Your role requires two-factor authentication")
+)
+
+// twoFactorInput is the second factor sent to confirm a sensitive action
+type twoFactorInput struct {
+	Code         string `json:"code"`          // from the authenticator app
+	RecoveryCode string `json:"recovery_code"` // one of the codes issued at enrollment
+}
+
+// verifySecondFactor checks a TOTP code or recovery code for user and uses it up
+func verifySecondFactor(db *gorm.DB, user models.User, input twoFactorInput) error {
+	switch {
+	case input.Code != "":
+		step, ok := utils.ValidateTOTP(user.TOTPSecret, input.Code, time.Now())
+		if !ok {
+			return errTwoFactorCodeInvalid
+		}
+		// Conditional on the last step so a code can't be replayed, even concurrently
+		result := db.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errTwoFactorCodeInvalid
+		}
+		return nil
+
+	case input.RecoveryCode != "":
+		hash := utils.HashToken(utils.NormalizeRecoveryCode(input.RecoveryCode))
+		result := db.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hash).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errTwoFactorCodeInvalid
+		}
+		return nil
+
+	default:
+		return errTwoFactorCodeRequired
+	}
+}
+
+// replaceRecoveryCodes discards a user's recovery codes and returns a fresh set
+func replaceRecoveryCodes(tx *gorm.DB, userID int) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(code)}
+	}
+	return codes, tx.Create(&rows).Error
+}
+
+// POST /login/2fa - Finish a login that was answered with a two-factor challenge
+func LoginTwoFactor(c *gin.Context) {
+	var input struct {
+		TwoFactorToken string `json:"two_factor_token" binding:"required"`
+		twoFactorInput
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor token is required"})
+		return
+	}
+
+	userID, err := utils.ParseTwoFactorToken(input.TwoFactorToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired two-factor token"})
+		return
+	}
+
+	// Codes are throttled per user, since a stolen password gets many tokens
+	throttleKeys, ok := twoFactorThrottle(c, userID)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := utils.GormDB.First(&user, userID).Error; err != nil || !user.TwoFactorEnabled() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired two-factor token"})
+		return
+	}
+
+	err = verifySecondFactor(utils.GormDB, user, input.twoFactorInput)
+	if err == errTwoFactorCodeRequired {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err == errTwoFactorCodeInvalid {
+		recordLoginFailure(c, throttleKeys)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error verifying second factor:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not log in"})
+		return
+	}
+
+	clearLoginFailures(twoFactorAccount(userID))
+	user.Password = ""
+	// A ban may have come in since the password was checked
+	if refuseBanned(c, user.ID) {
+		return
+	}
+	startAuthSession(c, http.StatusOK, user)
+}
+
+// GET /account/2fa - Show whether two-factor authentication is on
+func GetTwoFactorStatus(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := utils.GormDB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var remaining int64
+	if err := utils.GormDB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).Count(&remaining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve two-factor status"})
+		return
+	}
+
+	required, err := middleware.TwoFactorRequired(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TwoFactorEnabled(),
+		"enabled_at":               user.TOTPEnabledAt,
+		"required":                 required,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// POST /account/2fa/setup - Create a TOTP secret to scan into an authenticator app
+func SetupTwoFactor(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := utils.GormDB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TwoFactorEnabled() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+	// Pending until confirmed with a code; running setup again replaces it
+	if err := utils.GormDB.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	if account == "" {
+		account = fmt.Sprintf("user-%d", user.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(config.TOTPIssuer, account, secret),
+	})
+}
+
+// POST /account/2fa/enable - Confirm setup with a first code and receive recovery codes
+func EnableTwoFactor(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	// A stolen access token mustn't allow unlimited guesses at codes
+	throttleKeys, ok := twoFactorThrottle(c, int(userID))
+	if !ok {
+		return
+	}
+
+	var codes []string
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.TwoFactorEnabled() {
+			return errTwoFactorEnabled
+		}
+		if user.TOTPSecret == "" {
+			return errTwoFactorNotSetUp
+		}
+
+		if err := verifySecondFactor(tx, user, twoFactorInput{Code: input.Code}); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("totp_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		generated, err := replaceRecoveryCodes(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		codes = generated
+		// Sessions that logged in with only a password don't count any more
+		return revokeUserSessions(tx, user.ID, c.GetUint("sessionID"))
+	})
+	if err == errTwoFactorCodeInvalid {
+		recordLoginFailure(c, throttleKeys)
+	}
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	clearLoginFailures(twoFactorAccount(int(userID)))
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// POST /account/2fa/disable - Turn two-factor authentication off, confirmed by a code
+func DisableTwoFactor(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input twoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	// A stolen access token mustn't allow unlimited guesses at codes
+	throttleKeys, ok := twoFactorThrottle(c, int(userID))
+	if !ok {
+		return
+	}
+
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.TwoFactorEnabled() {
+			return errTwoFactorNotSetUp
+		}
+
+		required, err := middleware.TwoFactorRequired(user.Role)
+		if err != nil {
+			return err
+		}
+		if required {
+			return errTwoFactorMandatory
+		}
+
+		if err := verifySecondFactor(tx, user, input); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error
+	})
+	if err == errTwoFactorCodeInvalid {
+		recordLoginFailure(c, throttleKeys)
+	}
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	clearLoginFailures(twoFactorAccount(int(userID)))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// POST /account/2fa/recovery-codes - Replace the recovery codes, confirmed by a code
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input twoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	// A stolen access token mustn't allow unlimited guesses at codes
+	throttleKeys, ok := twoFactorThrottle(c, int(userID))
+	if !ok {
+		return
+	}
+
+	var codes []string
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.TwoFactorEnabled() {
+			return errTwoFactorNotSetUp
+		}
+
+		if err := verifySecondFactor(tx, user, input); err != nil {
+			return err
+		}
+		generated, err := replaceRecoveryCodes(tx, user.ID)
+		codes = generated
+		return err
+	})
+	if err == errTwoFactorCodeInvalid {
+		recordLoginFailure(c, throttleKeys)
+	}
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	clearLoginFailures(twoFactorAccount(int(userID)))
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// twoFactorAccount names the login throttle counter of userID's codes
+func twoFactorAccount(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// twoFactorThrottle returns the counters a code entered for userID is checked
+// against, the same whether it is entered to log in or to change two-factor
+// settings. When any is locked it writes the response and returns false.
+func twoFactorThrottle(c *gin.Context, userID int) ([]throttleKey, bool) {
+	keys := loginThrottleKeys(c, twoFactorAccount(userID))
+	wait, err := loginLockedFor(keys)
+	if err != nil {
+		log.Println("Error checking login throttle:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check code"})
+		return nil, false
+	}
+	if wait > 0 {
+		writeLoginLocked(c, wait)
+		return nil, false
+	}
+	return keys, true
+}
+
+// writeTwoFactorError maps a two-factor failure to a response
+func writeTwoFactorError(c *gin.Context, err error) {
+	switch err {
+	case errTwoFactorCodeRequired:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errTwoFactorCodeInvalid:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errTwoFactorEnabled, errTwoFactorNotSetUp:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errTwoFactorMandatory:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	default:
+		log.Println("Error updating two-factor authentication:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update two-factor authentication"})
+	}
+}
+
+// GET /admin/2fa-policy - List which roles must use two-factor authentication
+func GetTwoFactorPolicies(c *gin.Context) {
+	var policies []models.RolePolicy
+	if err := utils.GormDB.Order("role").Find(&policies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve policies"})
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+// PUT /admin/2fa-policy/:role - Require (or stop requiring) two-factor authentication for a role
+func SetTwoFactorPolicy(c *gin.Context) {
+	role := c.Param("role")
+	if !middleware.IsValidRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	var input struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "required must be true or false"})
+		return
+	}
+
+	policy := models.RolePolicy{Role: role, RequireTwoFactor: *input.Required}
+	if err := utils.GormDB.Save(&policy).Error; err != nil {
+		log.Println("Error saving role policy:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save policy"})
+		return
+	}
+
+	recordAudit(c, models.AuditTwoFactorPolicy, 0, models.JSONMap{"role": role, "required": policy.RequireTwoFactor})
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,498 @@
+package controllers
+
+import (
+	"Delingo/src/config"
+	"Delingo/src/middleware"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// adminUser is an account as admins see it in search results
+type adminUser struct {
+	ID                 int             `json:"id"`
+	Username           string          `json:"username"`
+	Email              string          `json:"email"`
+	RegistrationMethod string          `json:"registration_method"`
+	Role               string          `json:"role"`
+	CreatedAt          time.Time       `json:"created_at"`
+	TwoFactorEnabled   bool            `json:"two_factor_enabled"`
+	Ban                *models.UserBan `json:"ban,omitempty" gorm:"-"` // the ban in force, if any
+}
+
+// GET /admin/users - Search accounts by email, username or wallet address (?q=),
+// filtered by ?registration_method= and ?role=, newest first, paged with ?cursor=&limit=
+func SearchUsers(c *gin.Context) {
+	cursor, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	query := utils.GormDB.Model(&models.User{}).
+		Select("users.id, users.username, users.email, users.registration_method, users.role, users.created_at, " +
+			"users.totp_enabled_at IS NOT NULL AS two_factor_enabled").
+		Order("users.id DESC").Limit(limit + 1)
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q != "" {
+		// Identities hold every email and wallet linked to the account
+		pattern := "%" + escapeLike(q) + "%"
+		query = query.Where("users.username ILIKE ? OR users.email ILIKE ? OR EXISTS "+
+			"(SELECT 1 FROM user_identities i WHERE i.user_id = users.id AND i.subject ILIKE ?)",
+			pattern, pattern, pattern)
+	}
+	method := c.Query("registration_method")
+	if method != "" {
+		if method != models.ProviderEmail && method != models.ProviderEthereum && method != models.ProviderSolana {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "registration_method must be email, ethereum or solana"})
+			return
+		}
+		query = query.Where("users.registration_method = ?", method)
+	}
+	role := c.Query("role")
+	if role != "" {
+		if !middleware.IsValidRole(role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+			return
+		}
+		query = query.Where("users.role = ?", role)
+	}
+	if cursor > 0 {
+		query = query.Where("users.id < ?", cursor)
+	}
+
+	var users []adminUser
+	if err := query.Scan(&users).Error; err != nil {
+		log.Println("Error searching users:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+		return
+	}
+
+	var next *string
+	if len(users) > limit {
+		users = users[:limit]
+		next = cursorString(uint(users[limit-1].ID))
+	}
+	if users == nil {
+		users = []adminUser{}
+	}
+
+	// Show who is currently banned
+	ids := make([]int, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	bans, err := activeBans(ids)
+	if err != nil {
+		log.Println("Error loading bans:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+		return
+	}
+	for i := range users {
+		users[i].Ban = bans[users[i].ID]
+	}
+
+	recordAudit(c, models.AuditUserSearch, 0, models.JSONMap{
+		"q": q, "registration_method": method, "role": role, "results": len(users),
+	})
+	c.JSON(http.StatusOK, gin.H{"users": users, "next_cursor": next})
+}
+
+// GET /admin/users/:id - An account with its identities, sessions and ban history
+func GetUserDetails(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user adminUser
+	result := utils.GormDB.Model(&models.User{}).
+		Select("id, username, email, registration_method, role, created_at, totp_enabled_at IS NOT NULL AS two_factor_enabled").
+		Where("id = ?", userID).Scan(&user)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var identities []models.UserIdentity
+	var sessions []models.Session
+	var bans []models.UserBan
+	var resetRequired bool
+	err = utils.GormDB.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	if err == nil {
+		err = utils.GormDB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+			Order("last_seen_at DESC").Find(&sessions).Error
+	}
+	if err == nil {
+		err = utils.GormDB.Where("user_id = ?", userID).Order("created_at DESC").Find(&bans).Error
+	}
+	if err == nil {
+		err = utils.GormDB.Model(&models.User{}).Where("id = ?", userID).
+			Select("password_reset_required").Scan(&resetRequired).Error
+	}
+	if err != nil {
+		log.Println("Error loading user details:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	for i := range bans {
+		if bans[i].Active(time.Now()) {
+			user.Ban = &bans[i]
+			break
+		}
+	}
+
+	recordAudit(c, models.AuditUserView, userID, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"user":                    user,
+		"identities":              identities,
+		"sessions":                sessions,
+		"bans":                    bans,
+		"password_reset_required": resetRequired,
+	})
+}
+
+// POST /admin/users/:id/ban - Suspend a user for a duration (e.g. "72h"), or ban
+// them for good when no duration is given. Their sessions end at once.
+func BanUser(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason   string `json:"reason" binding:"required"`
+		Duration string `json:"duration"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+	if utf8.RuneCountInString(input.Reason) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be at most 500 characters"})
+		return
+	}
+
+	now := time.Now()
+	ban := models.UserBan{UserID: userID, Reason: strings.TrimSpace(input.Reason), BannedBy: adminID}
+	if input.Duration != "" {
+		d, err := time.ParseDuration(input.Duration)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be a positive duration such as \"72h\""})
+			return
+		}
+		expires := now.Add(d)
+		ban.ExpiresAt = &expires
+	}
+
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		// A new ban replaces the one in force
+		if err := liftBans(tx, userID, adminID, now); err != nil {
+			return err
+		}
+		if err := tx.Create(&ban).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, userID, 0)
+	})
+	if err != nil {
+		log.Println("Error banning user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ban user"})
+		return
+	}
+
+	recordAudit(c, models.AuditUserBan, userID, models.JSONMap{
+		"ban_id": ban.ID, "reason": ban.Reason, "expires_at": ban.ExpiresAt,
+	})
+	c.JSON(http.StatusCreated, ban)
+}
+
+// DELETE /admin/users/:id/ban - Lift a user's ban or suspension
+func UnbanUser(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	result := utils.GormDB.Model(&models.UserBan{}).
+		Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Updates(map[string]interface{}{"lifted_at": time.Now(), "lifted_by": adminID})
+	if result.Error != nil {
+		log.Println("Error lifting ban:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lift ban"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not banned"})
+		return
+	}
+
+	recordAudit(c, models.AuditUserUnban, userID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Ban lifted"})
+}
+
+// POST /admin/users/:id/password-reset - Make a user choose a new password: their
+// sessions end, their password stops working and a reset link is emailed to them
+func ForcePasswordReset(c *gin.Context) {
+	_, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	// The link goes to a verified address if the account has one
+	var identity models.UserIdentity
+	err := utils.GormDB.Where("user_id = ? AND provider = ?", userID, models.ProviderEmail).
+		Order("verified_at IS NULL, created_at").First(&identity).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This account has no email and password to reset"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password_reset_required", true).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, userID, 0)
+	})
+	if err != nil {
+		log.Println("Error forcing password reset:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	sendPasswordResetEmail(userID, identity.Subject)
+
+	recordAudit(c, models.AuditPasswordReset, userID, models.JSONMap{"email": identity.Subject})
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset required; a reset link was emailed to the user"})
+}
+
+// POST /admin/users/:id/impersonate - Get a short-lived access token for acting
+// as a user, to see what they see. The session is marked with the admin, can't
+// be refreshed, and can't change the account's security settings. Every
+// request made with it is audited by middleware.AuditImpersonation.
+func ImpersonateUser(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	var user models.User
+	if err := utils.GormDB.Select("id", "role").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	// Acting as another admin would hand out their powers
+	if middleware.HasPermission(user.Role, middleware.PermUsersManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admins can't be impersonated"})
+		return
+	}
+
+	// No refresh token is issued; the session ends with its access token
+	now := time.Now()
+	_, refreshHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		return
+	}
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        c.Request.UserAgent(),
+		IPAddress:        c.ClientIP(),
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(config.AccessTokenTTL),
+		ImpersonatorID:   &adminID,
+	}
+	if err := utils.GormDB.Create(&session).Error; err != nil {
+		log.Println("Error starting impersonation:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		return
+	}
+	accessToken, err := utils.GenerateToken(user.ID, session.ID, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		return
+	}
+
+	recordAudit(c, models.AuditImpersonate, userID, models.JSONMap{
+		"session_id": session.ID, "reason": strings.TrimSpace(input.Reason),
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(config.AccessTokenTTL.Seconds()),
+	})
+}
+
+// GET /admin/audit-log - Administrative actions, newest first, filtered by
+// ?actor_id=, ?target_user_id= and ?action=, paged with ?cursor=&limit=
+func GetAuditLog(c *gin.Context) {
+	cursor, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	query := utils.GormDB.Order("id DESC").Limit(limit + 1)
+	for _, filter := range []string{"actor_id", "target_user_id"} {
+		if raw := c.Query(filter); raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + filter})
+				return
+			}
+			query = query.Where(filter+" = ?", id)
+		}
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+
+	var entries []models.AuditLog
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit log"})
+		return
+	}
+
+	var next *string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		next = cursorString(entries[limit-1].ID)
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "next_cursor": next})
+}
+
+// adminTarget reads the admin making the request and the :id user they act on,
+// who must exist and can't be the admin themselves. On failure it writes the
+// response and returns false.
+func adminTarget(c *gin.Context) (int, int, bool) {
+	adminID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, 0, false
+	}
+	if userID == int(adminID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't do this to your own account"})
+		return 0, 0, false
+	}
+
+	var exists bool
+	if err := utils.SQLDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return 0, 0, false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return 0, 0, false
+	}
+	return int(adminID), userID, true
+}
+
+// activeBans returns the ban in force for each of the given users that has one
+func activeBans(userIDs []int) (map[int]*models.UserBan, error) {
+	bans := map[int]*models.UserBan{}
+	if len(userIDs) == 0 {
+		return bans, nil
+	}
+	var found []models.UserBan
+	err := utils.GormDB.Where("user_id IN ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userIDs, time.Now()).
+		Order("created_at").Find(&found).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		bans[found[i].UserID] = &found[i]
+	}
+	return bans, nil
+}
+
+// liftBans ends every ban in force for userID
+func liftBans(tx *gorm.DB, userID, adminID int, now time.Time) error {
+	return tx.Model(&models.UserBan{}).
+		Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Updates(map[string]interface{}{"lifted_at": now, "lifted_by": adminID}).Error
+}
+
+// refuseBanned answers a login for a banned or suspended user and returns true.
+// It returns false, writing nothing, when the user may log in.
+func refuseBanned(c *gin.Context, userID int) bool {
+	bans, err := activeBans([]int{userID})
+	if err != nil {
+		log.Println("Error checking bans:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not log in"})
+		return true
+	}
+	ban := bans[userID]
+	if ban == nil {
+		return false
+	}
+
+	if ban.ExpiresAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account has been banned: " + ban.Reason})
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":           fmt.Sprintf("This account is suspended until %s: %s", ban.ExpiresAt.UTC().Format(time.RFC1123), ban.Reason),
+		"suspended_until": ban.ExpiresAt,
+	})
+	return true
+}
+
+// recordAudit adds an admin action to the audit log. targetUserID is 0 when the
+// action isn't about one user. Failures are logged; the action already happened.
+func recordAudit(c *gin.Context, action string, targetUserID int, details models.JSONMap) {
+	actorID, err := getUserFromToken(c)
+	if err != nil {
+		log.Println("Error recording audit entry: no actor for", action)
+		return
+	}
+	entry := models.AuditLog{
+		ActorID:   int(actorID),
+		Action:    action,
+		Details:   details,
+		IPAddress: c.ClientIP(),
+	}
+	if targetUserID != 0 {
+		entry.TargetUserID = &targetUserID
+	}
+	if err := utils.GormDB.Create(&entry).Error; err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern so user input matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
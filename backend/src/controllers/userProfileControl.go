@@ -3,12 +3,17 @@ package controllers
 import (
 	"Delingo/src/models" // Import models for Profile
 	"Delingo/src/utils"  // Database utils
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin" // Add the gin import
+	"gorm.io/gorm"
 )
 
 // GET /profile - Fetch the user's profile
@@ -20,14 +25,12 @@ func GetUserProfile(c *gin.Context) {
 		return
 	}
 
-	// Check if the user exists before fetching profile
-	if !userExists(c, userID) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
 	// Query the DB for the user's profile
 	profile, err := GetUserProfileFromDB(userID)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user profile"})
 		return
@@ -37,7 +40,121 @@ func GetUserProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, profile)
 }
 
-// PUT /profile - Update the user's profile
+// profileUpdate lists the profile fields a client may change. A missing field is
+// left as it is, and preferences, social_links and privacy are merged key by key.
+// Level, streak and avatar_url are only here so setting them can be refused clearly.
+type profileUpdate struct {
+	Bio         *string         `json:"bio"`
+	AvatarURL   *string         `json:"avatar_url"`
+	Location    *string         `json:"location"`
+	Website     *string         `json:"website"`
+	Preferences json.RawMessage `json:"preferences"`
+	SocialLinks json.RawMessage `json:"social_links"`
+	Privacy     json.RawMessage `json:"privacy"`
+	Level       *int            `json:"level"`
+	Streak      *int            `json:"streak"`
+}
+
+// changes validates the update against the current profile and returns the columns to set
+func (u profileUpdate) changes(current models.Profile) (map[string]interface{}, error) {
+	if u.Level != nil {
+		return nil, errors.New("level is managed by the server and cannot be changed")
+	}
+	if u.Streak != nil {
+		return nil, errors.New("streak is managed by the server and cannot be changed")
+	}
+	if u.AvatarURL != nil {
+		return nil, errors.New("avatar_url is set by uploading an image to /api/profile/avatar")
+	}
+
+	changes := map[string]interface{}{}
+	text := []struct {
+		column string
+		value  *string
+		max    int
+	}{
+		{"bio", u.Bio, 500},
+		{"location", u.Location, 100},
+	}
+	for _, f := range text {
+		if f.value == nil {
+			continue
+		}
+		if utf8.RuneCountInString(*f.value) > f.max {
+			return nil, fmt.Errorf("%s must be at most %d characters", f.column, f.max)
+		}
+		changes[f.column] = *f.value
+	}
+
+	links := []struct {
+		column string
+		value  *string
+	}{
+		{"website", u.Website},
+	}
+	for _, f := range links {
+		if f.value == nil {
+			continue
+		}
+		if *f.value != "" && !isWebURL(*f.value) {
+			return nil, fmt.Errorf("%s must be an http or https URL", f.column)
+		}
+		changes[f.column] = *f.value
+	}
+
+	if len(u.Preferences) > 0 {
+		prefs := current.Preferences
+		if err := decodeStrict(u.Preferences, &prefs); err != nil {
+			return nil, fmt.Errorf("preferences: %v", err)
+		}
+		if err := prefs.Validate(); err != nil {
+			return nil, err
+		}
+		changes["preferences"] = prefs
+	}
+
+	if len(u.SocialLinks) > 0 {
+		social := current.SocialLinks
+		if err := decodeStrict(u.SocialLinks, &social); err != nil {
+			return nil, fmt.Errorf("social_links: %v", err)
+		}
+		if err := social.Validate(); err != nil {
+			return nil, err
+		}
+		changes["social_links"] = social
+	}
+
+	if len(u.Privacy) > 0 {
+		privacy := current.Privacy
+		if err := decodeStrict(u.Privacy, &privacy); err != nil {
+			return nil, fmt.Errorf("privacy: %v", err)
+		}
+		if err := privacy.Validate(); err != nil {
+			return nil, err
+		}
+		changes["privacy"] = privacy
+	}
+
+	return changes, nil
+}
+
+// decodeStrict unmarshals JSON into v, rejecting fields v doesn't have
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// isWebURL reports whether s is an absolute http(s) URL of reasonable length
+func isWebURL(s string) bool {
+	if len(s) > 2048 {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// PATCH /profile - Change only the profile fields present in the request
 func UpdateUserProfile(c *gin.Context) {
 	// Get the user from the token
 	userID, err := getUserFromToken(c)
@@ -46,91 +163,79 @@ func UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
-	// Check if the user exists before updating
-	if !userExists(c, userID) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	// Decode the request body, refusing fields that aren't editable (id, user_id, ...)
+	var update profileUpdate
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if err := decodeStrict(body, &update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var newProfile models.Profile // Declare a variable to hold the updated profile
+	// Settings are merged into what is stored, so start from the current profile
+	current, err := GetUserProfileFromDB(userID)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user profile"})
+		return
+	}
 
-	// Decode the request body into the Profile model
-	if err := json.NewDecoder(c.Request.Body).Decode(&newProfile); err != nil {
+	changes, err := update.changes(current)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Call the function to update the user's profile
-	err = UpdateUserProfileInDB(userID, newProfile)
+	profile, err := UpdateUserProfileInDB(userID, changes)
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user profile"})
 		return
 	}
 
-	// Respond with success
-	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
+	// Respond with the profile as it now is
+	c.JSON(http.StatusOK, profile)
+}
+
+// createProfile gives a newly registered user their empty profile
+func createProfile(tx *gorm.DB, userID int) error {
+	return tx.Create(&models.Profile{UserID: userID}).Error
 }
 
 // Fetch user profile from the DB
 func GetUserProfileFromDB(userID uint) (models.Profile, error) {
 	var profile models.Profile
-	query := `SELECT level, avatar_url, bio, preferences, location, website, social_links 
-			  FROM profiles WHERE user_id = $1`
-
-	row := utils.SQLDB.QueryRow(query, userID)
-	err := row.Scan(&profile.Level, &profile.AvatarURL, &profile.Bio, &profile.Preferences,
-		&profile.Location, &profile.Website, &profile.SocialLinks)
-
-	if err != nil {
+	err := utils.GormDB.Where("user_id = ?", userID).First(&profile).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
 		log.Println("Error fetching user profile:", err)
-		return profile, err
-	}
-
-	return profile, nil
-}
-
-// Update user profile in the DB
-func UpdateUserProfileInDB(userID uint, newProfile models.Profile) error {
-	// Ensure the user exists before attempting to update the profile
-	if !userExists(nil, userID) {
-		return fmt.Errorf("user does not exist")
 	}
-
-	query := `UPDATE profiles 
-			  SET level = $1, avatar_url = $2, bio = $3, preferences = $4, location = $5, website = $6, social_links = $7, updated_at = NOW() 
-			  WHERE user_id = $8`
-
-	_, err := utils.SQLDB.Exec(query,
-		newProfile.Level,
-		newProfile.AvatarURL,
-		newProfile.Bio,
-		newProfile.Preferences,
-		newProfile.Location,
-		newProfile.Website,
-		newProfile.SocialLinks,
-		userID,
-	)
-
-	if err != nil {
-		log.Println("Error updating profile:", err)
-		return err
-	}
-
-	return nil
+	return profile, err
 }
 
-// Check if the user exists in the users table
-func userExists(c *gin.Context, userID uint) bool {
-	var exists bool
-	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`
-	err := utils.SQLDB.QueryRow(query, userID).Scan(&exists)
-	if err != nil || !exists {
-		if c != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+// Update the given profile columns in the DB and return the result
+func UpdateUserProfileInDB(userID uint, changes map[string]interface{}) (models.Profile, error) {
+	if len(changes) > 0 {
+		result := utils.GormDB.Model(&models.Profile{}).Where("user_id = ?", userID).Updates(changes)
+		if result.Error != nil {
+			log.Println("Error updating profile:", result.Error)
+			return models.Profile{}, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.Profile{}, gorm.ErrRecordNotFound
 		}
-		return false
 	}
-	return true
+
+	return GetUserProfileFromDB(userID)
 }
 
 // getUserFromToken extracts the user ID from the JWT token in the context
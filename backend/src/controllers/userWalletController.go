@@ -3,87 +3,75 @@ package controllers
 import (
 	"Delingo/src/models"
 	"Delingo/src/utils"
-	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
-	"github.com/gorilla/mux"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
 )
 
-// RegisterWalletUser handles wallet-based user registration
-func RegisterWalletUser(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
-	}
+// GET /wallet/nonce - Issue a nonce for a Sign-In With Ethereum message
+func GetWalletNonce(c *gin.Context) {
+	issueSignInNonce(c, models.ProviderEthereum)
+}
 
-	// Ensure the user provides a wallet address (Ethereum or Solana)
-	if user.EthereumWalletAddr == "" && user.SolanaWalletAddr == "" {
-		http.Error(w, "Ethereum or Solana wallet address required", http.StatusBadRequest)
+// POST /wallet/verify - Log in an Ethereum wallet user from a signed EIP-4361 message,
+// creating the account on first sign-in
+func VerifyWalletSignIn(c *gin.Context) {
+	var input struct {
+		Message   string `json:"message"`
+		Signature string `json:"signature"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	// Clear email and password for wallet-based registration
-	user.Email = ""
-	user.Password = ""
-
-	// Set registration method to wallet
-	user.RegistrationMethod = "wallet"
-
-	// Save user to the database, choosing the correct wallet address
-	query := `INSERT INTO users (username, email, ethereum_wallet_address, solana_wallet_address, registration_method, created_at) 
-              VALUES ($1, $2, $3, $4, $5, NOW()) RETURNING id`
-	err = utils.SQLDB.QueryRow(query, user.Username, user.Email, user.EthereumWalletAddr, user.SolanaWalletAddr, user.RegistrationMethod).Scan(&user.ID)
+	address, err := verifyEthereumSignIn(input.Message, input.Signature)
 	if err != nil {
-		http.Error(w, "Could not create user", http.StatusInternalServerError)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Respond with user details
-	json.NewEncoder(w).Encode(user)
+	signInWithIdentity(c, models.ProviderEthereum, address.Hex())
 }
 
-// UpdateWalletAddress updates a user's wallet address
-func UpdateWalletAddress(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from the URL
-	vars := mux.Vars(r)
-	userID := vars["id"]
+// GET /wallet/address/:address - Retrieve a user by a linked Ethereum wallet address
+func GetWalletUser(c *gin.Context) {
+	getUserByIdentity(c, models.ProviderEthereum)
+}
 
-	// Parse the request body into a user object
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
+// verifyEthereumSignIn checks a SIWE message and signature and returns the proven address
+func verifyEthereumSignIn(message, signature string) (common.Address, error) {
+	msg, err := utils.ParseSignInMessage(message)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return common.Address{}, err
+	}
+	if err := msg.Validate(models.ProviderEthereum, time.Now()); err != nil {
+		return common.Address{}, err
+	}
+	if !common.IsHexAddress(msg.Address) {
+		return common.Address{}, errors.New("message address is not an Ethereum address")
 	}
 
-	// Determine which wallet address to update
-	var query string
-	var walletAddress string
-
-	// If Ethereum wallet address is provided, update it
-	if user.EthereumWalletAddr != "" {
-		query = `UPDATE users SET ethereum_wallet_address=$1 WHERE id=$2`
-		walletAddress = user.EthereumWalletAddr
-	} else if user.SolanaWalletAddr != "" {
-		// If Solana wallet address is provided, update it
-		query = `UPDATE users SET solana_wallet_address=$1 WHERE id=$2`
-		walletAddress = user.SolanaWalletAddr
-	} else {
-		// If neither address is provided, return an error
-		http.Error(w, "No wallet address provided", http.StatusBadRequest)
-		return
+	// The recovered signer must be the address named in the message
+	signer, err := utils.VerifyEthereumSignature(message, signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signer != common.HexToAddress(msg.Address) {
+		return common.Address{}, errors.New("signature does not match address")
 	}
 
-	// Execute the update query
-	_, err = utils.SQLDB.Exec(query, walletAddress, userID)
+	// Only burn the nonce once everything else checks out
+	ok, err := consumeSignInNonce(msg.Nonce, models.ProviderEthereum)
 	if err != nil {
-		http.Error(w, "Could not update wallet address", http.StatusInternalServerError)
-		return
+		return common.Address{}, errors.New("could not check nonce")
+	}
+	if !ok {
+		return common.Address{}, errors.New("nonce is invalid or expired")
 	}
 
-	// Send the response back
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(user)
+	return signer, nil
 }
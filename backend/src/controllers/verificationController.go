@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"Delingo/src/config"
+	"Delingo/src/mailer"
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errUserTokenInvalid is returned for unknown, expired or already used emailed tokens
+var errUserTokenInvalid = errors.New("This link is invalid or has expired")
+
+// issueUserToken creates a single-use token for purpose, replacing any earlier
+// unused token the user had for the same purpose
+func issueUserToken(userID int, purpose, subject string, ttl time.Duration) (string, error) {
+	token, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			Subject:   subject,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	return token, err
+}
+
+// consumeUserToken marks a token used and returns it. Each token works once.
+func consumeUserToken(tx *gorm.DB, purpose, token string) (models.UserToken, error) {
+	var userToken models.UserToken
+	err := tx.Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).First(&userToken).Error
+	if err == gorm.ErrRecordNotFound {
+		return userToken, errUserTokenInvalid
+	}
+	if err != nil {
+		return userToken, err
+	}
+
+	// Conditional update so the same token can't be redeemed twice concurrently
+	result := tx.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", userToken.ID, time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return userToken, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return userToken, errUserTokenInvalid
+	}
+	return userToken, nil
+}
+
+// sendVerificationEmail emails a link that confirms the user owns email.
+// Failures are logged rather than returned so they never block signup.
+func sendVerificationEmail(userID int, email string) {
+	token, err := issueUserToken(userID, models.TokenEmailVerification, email, config.EmailVerificationTTL)
+	if err != nil {
+		log.Println("Error issuing verification token:", err)
+		return
+	}
+
+	link := config.AppBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+	err = mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Confirm your Delingo email address",
+		Body: fmt.Sprintf("Welcome to Delingo!\n\nConfirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %s. If you did not sign up, you can ignore this email.\n",
+			link, humanizeDuration(config.EmailVerificationTTL)),
+	})
+	if err != nil {
+		log.Println("Error sending verification email:", err)
+	}
+}
+
+// sendPasswordResetEmail emails a link for choosing a new password. Like
+// sendVerificationEmail it logs failures, as nobody waits on it.
+func sendPasswordResetEmail(userID int, email string) {
+	token, err := issueUserToken(userID, models.TokenPasswordReset, email, config.PasswordResetTTL)
+	if err != nil {
+		log.Println("Error issuing password reset token:", err)
+		return
+	}
+
+	link := config.AppBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	err = mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Reset your Delingo password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Delingo account.\n\n"+
+			"Choose a new password by opening this link:\n\n%s\n\n"+
+			"The link expires in %s. If it wasn't you, you can ignore this email.\n",
+			link, humanizeDuration(config.PasswordResetTTL)),
+	})
+	if err != nil {
+		log.Println("Error sending password reset email:", err)
+	}
+}
+
+// humanizeDuration renders a link lifetime for an email, e.g. "24 hours"
+func humanizeDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d minutes", int(d.Minutes()))
+}
+
+// POST /verify-email - Confirm an email identity from the token in a verification link
+func VerifyEmail(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := consumeUserToken(tx, models.TokenEmailVerification, input.Token)
+		if err != nil {
+			return err
+		}
+
+		// Only the address the link was sent to is verified, in case it was unlinked since
+		result := tx.Model(&models.UserIdentity{}).
+			Where("user_id = ? AND provider = ? AND subject = ?", userToken.UserID, models.ProviderEmail, userToken.Subject).
+			Update("verified_at", gorm.Expr("COALESCE(verified_at, ?)", time.Now()))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUserTokenInvalid
+		}
+		return nil
+	})
+	if err == errUserTokenInvalid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error verifying email:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// POST /account/email/verification - Send a fresh verification link
+func ResendVerificationEmail(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var identity models.UserIdentity
+	err = utils.GormDB.Where("user_id = ? AND provider = ?", userID, models.ProviderEmail).First(&identity).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No email is linked to this account"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	if identity.VerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	sendVerificationEmail(identity.UserID, identity.Subject)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// POST /password/forgot - Email a password reset link. It answers the same way
+// whether or not the address is registered so accounts can't be enumerated.
+func ForgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		return
+	}
+
+	var identity models.UserIdentity
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	err := utils.GormDB.Where("provider = ? AND subject = ?", models.ProviderEmail, email).First(&identity).Error
+	if err == nil {
+		// Issue and send in the background so a registered email answers as
+		// quickly as an unknown one and the timing doesn't give it away
+		go sendPasswordResetEmail(identity.UserID, identity.Subject)
+	} else if err != gorm.ErrRecordNotFound {
+		log.Println("Error looking up email for password reset:", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered, a reset link is on its way"})
+}
+
+// POST /password/reset - Set a new password from the token in a reset link and
+// sign out every existing session
+func ResetPassword(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token and password are required"})
+		return
+	}
+
+	hash, err := utils.HashPassword(input.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var resetEmail string
+	err = utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := consumeUserToken(tx, models.TokenPasswordReset, input.Token)
+		if err != nil {
+			return err
+		}
+
+		// The link must still point at the account's current email
+		var identity models.UserIdentity
+		err = tx.Where("user_id = ? AND provider = ? AND subject = ?", userToken.UserID, models.ProviderEmail, userToken.Subject).
+			First(&identity).Error
+		if err == gorm.ErrRecordNotFound {
+			return errUserTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		// This also satisfies a reset forced by an admin
+		err = tx.Model(&models.User{}).Where("id = ?", userToken.UserID).
+			Updates(map[string]interface{}{"password": hash, "password_reset_required": false}).Error
+		if err != nil {
+			return err
+		}
+		// Following the emailed link also proves the address
+		if identity.VerifiedAt == nil {
+			if err := tx.Model(&identity).Update("verified_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+		resetEmail = identity.Subject
+		return revokeUserSessions(tx, userToken.UserID, 0)
+	})
+	if err == errUserTokenInvalid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Error resetting password:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reset password"})
+		return
+	}
+	// The owner proved themselves, so lift any lockout on their account
+	clearLoginFailures(resetEmail)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated"})
+}
@@ -0,0 +1,407 @@
+package controllers
+
+import (
+	"Delingo/src/events"
+	"Delingo/src/models"
+	"Delingo/src/streaks"
+	"Delingo/src/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	lessonXP         = 10  // for finishing a lesson
+	perfectLessonXP  = 5   // extra for finishing one without a mistake
+	forumUpvoteXP    = 2   // for each upvote on a thread or post
+	maxStreakBonusXP = 100 // a streak milestone is worth its days in XP, up to this
+	maxGrantXP       = 1000
+	maxXPHistoryDays = 366
+	leaderboardSize  = 50
+)
+
+// xpSummary is a learner's XP totals, by their own calendar
+type xpSummary struct {
+	Today       int  `json:"today"`
+	DailyGoal   int  `json:"daily_goal"`
+	GoalMet     bool `json:"goal_met"`
+	Week        int  `json:"week"` // since Monday
+	AllTime     int  `json:"all_time"`
+	Level       int  `json:"level"`
+	NextLevelXP int  `json:"next_level_xp"` // the all-time XP the next level needs
+}
+
+// xpDay is the XP earned on one day
+type xpDay struct {
+	Day     string `json:"day"`
+	XP      int    `json:"xp"`
+	GoalMet bool   `json:"goal_met"`
+}
+
+// leaderboardEntry is a learner's place in a week's league table
+type leaderboardEntry struct {
+	UserID    int    `json:"-"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+	XP        int    `json:"xp"`
+}
+
+// GET /xp - The caller's XP today, this week and in all, with their daily goal
+func GetXP(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := learnerPreferences(utils.GormDB, int(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve XP"})
+		return
+	}
+	today := streaks.Day(time.Now(), preferredLocation(prefs))
+
+	var summary xpSummary
+	err = utils.SQLDB.QueryRow(`
+		SELECT COALESCE(SUM(amount) FILTER (WHERE day = $2), 0),
+			COALESCE(SUM(amount) FILTER (WHERE day >= $3), 0),
+			COALESCE(SUM(amount), 0)
+		FROM xp_entries WHERE user_id = $1`,
+		userID, today, weekStart(today)).Scan(&summary.Today, &summary.Week, &summary.AllTime)
+	if err != nil {
+		log.Println("Error summing XP:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve XP"})
+		return
+	}
+	summary.DailyGoal = prefs.DailyXPGoal
+	summary.GoalMet = summary.Today >= prefs.DailyXPGoal
+	summary.Level = levelForXP(summary.AllTime)
+	summary.NextLevelXP = xpForLevel(summary.Level + 1)
+	c.JSON(http.StatusOK, summary)
+}
+
+// GET /xp/history - The caller's XP on each of the last ?days= days (30 by
+// default), oldest first, and whether each met the daily goal as it is now
+func GetXPHistory(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > maxXPHistoryDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", maxXPHistoryDays)})
+		return
+	}
+
+	prefs, err := learnerPreferences(utils.GormDB, int(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve XP history"})
+		return
+	}
+	today := streaks.Day(time.Now(), preferredLocation(prefs))
+	first := streaks.AddDays(today, 1-days)
+
+	var totals []struct {
+		Day string
+		XP  int
+	}
+	err = utils.GormDB.Model(&models.XPEntry{}).Select("day, SUM(amount) AS xp").
+		Where("user_id = ? AND day >= ? AND day <= ?", userID, first, today).
+		Group("day").Scan(&totals).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve XP history"})
+		return
+	}
+	byDay := map[string]int{}
+	for _, t := range totals {
+		byDay[t.Day] = t.XP
+	}
+
+	history := []xpDay{}
+	for i := 0; i < days; i++ {
+		day := streaks.AddDays(first, i)
+		history = append(history, xpDay{Day: day, XP: byDay[day], GoalMet: byDay[day] >= prefs.DailyXPGoal})
+	}
+	c.JSON(http.StatusOK, gin.H{"daily_goal": prefs.DailyXPGoal, "days": history})
+}
+
+// GET /xp/leaderboard - This week's league table of the caller and their
+// friends, by XP since Monday UTC. Friends whose level is private are left out.
+func GetXPLeaderboard(c *gin.Context) {
+	userID, err := getUserFromToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	monday := leagueWeek(time.Now())
+	entries, err := leagueTable(utils.GormDB, int(userID), monday, monday.AddDate(0, 0, 7), leaderboardSize)
+	if err != nil {
+		log.Println("Error loading leaderboard:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load leaderboard"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"week_start": monday, "entries": entries})
+}
+
+// leagueWeek returns the start of the league week t falls in: Monday, UTC
+func leagueWeek(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+}
+
+// leagueTable ranks userID and their friends by the XP they earned from from
+// until to, best first, up to limit places (-1 for all). Friends whose level
+// is private are left out, and so is anyone who earned nothing.
+func leagueTable(db *gorm.DB, userID int, from, to time.Time, limit int) ([]leaderboardEntry, error) {
+	friends := db.Table("follows f").Select("f.followee_id").
+		Joins("JOIN follows b ON b.follower_id = f.followee_id AND b.followee_id = f.follower_id").
+		Where("f.follower_id = ?", userID)
+
+	entries := []leaderboardEntry{}
+	err := db.Table("xp_entries").
+		Select("users.id AS user_id, users.username, COALESCE(profiles.avatar_url, '') AS avatar_url, SUM(xp_entries.amount) AS xp").
+		Joins("JOIN users ON users.id = xp_entries.user_id").
+		Joins("LEFT JOIN profiles ON profiles.user_id = xp_entries.user_id").
+		Where("xp_entries.created_at >= ? AND xp_entries.created_at < ?", from, to).
+		Where("xp_entries.user_id = ? OR (xp_entries.user_id IN (?) AND COALESCE(profiles.privacy->>'level', ?) <> ?)",
+			userID, friends, models.VisibilityPublic, models.VisibilityPrivate).
+		Group("users.id, users.username, profiles.avatar_url").
+		Order("xp DESC, users.username").Limit(limit).
+		Scan(&entries).Error
+	return entries, err
+}
+
+// recordLeagueResult puts the learner's place in last week's league table in
+// their friends' feeds. There is no job that closes the week, so the result is
+// recorded the first time the learner earns XP after it ends, and only if they
+// earned some during it. The table is of their friends as they are then.
+func recordLeagueResult(userID int, now time.Time) {
+	monday := leagueWeek(now)
+	lastWeek := monday.AddDate(0, 0, -7)
+	week := lastWeek.Format("2006-01-02")
+
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		// Lock the profile so concurrent awards record the result once
+		var profile models.Profile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("user_id = ?", userID).First(&profile).Error; err != nil {
+			return err
+		}
+		var recorded int64
+		err := tx.Model(&models.Activity{}).
+			Where("user_id = ? AND kind = ? AND data->>'week_start' = ?", userID, models.ActivityLeagueResult, week).
+			Count(&recorded).Error
+		if err != nil || recorded > 0 {
+			return err
+		}
+
+		table, err := leagueTable(tx, userID, lastWeek, monday, -1)
+		if err != nil {
+			return err
+		}
+		for i, entry := range table {
+			if entry.UserID == userID {
+				return tx.Create(&models.Activity{UserID: userID, Kind: models.ActivityLeagueResult, Data: models.JSONMap{
+					"week_start": week,
+					"rank":       i + 1,
+					"of":         len(table),
+					"xp":         entry.XP,
+				}}).Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Error recording league result:", err)
+	}
+}
+
+// POST /admin/users/:id/xp - Credit XP for a quiz solved on chain or a
+// promotional event. The ref names what it is for, e.g. "quiz:12", and each
+// ref is credited once.
+func GrantXP(c *gin.Context) {
+	_, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Source string `json:"source" binding:"required"`
+		Ref    string `json:"ref" binding:"required"`
+		Amount int    `json:"amount" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Ref) == "" || strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source, ref, amount and reason are required"})
+		return
+	}
+	if input.Source != models.XPQuiz && input.Source != models.XPEventBonus {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source must be quiz or event_bonus"})
+		return
+	}
+	if input.Amount < 1 || input.Amount > maxGrantXP {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("amount must be between 1 and %d", maxGrantXP)})
+		return
+	}
+	if utf8.RuneCountInString(input.Ref) > 100 || utf8.RuneCountInString(input.Reason) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ref must be at most 100 and reason at most 500 characters"})
+		return
+	}
+
+	ref := strings.TrimSpace(input.Ref)
+	awarded, err := awardXP(userID, input.Source, ref, input.Amount)
+	if err != nil {
+		log.Println("Error granting XP:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant XP"})
+		return
+	}
+	if !awarded {
+		c.JSON(http.StatusConflict, gin.H{"error": "That ref has already been credited"})
+		return
+	}
+	recordAudit(c, models.AuditXPGrant, userID, models.JSONMap{
+		"source": input.Source,
+		"ref":    ref,
+		"amount": input.Amount,
+		"reason": strings.TrimSpace(input.Reason),
+	})
+	c.JSON(http.StatusCreated, gin.H{"message": "XP granted"})
+}
+
+// awardXP adds an entry to the ledger, unless source and ref were already
+// awarded to the learner, and brings their level up to date. It reports
+// whether the XP was awarded; the events follow once it is committed.
+func awardXP(userID int, source, ref string, amount int) (bool, error) {
+	now := time.Now()
+	awarded := false
+	var entry models.XPEntry
+	var total, today, goal int
+	err := utils.GormDB.Transaction(func(tx *gorm.DB) error {
+		// Lock the profile so concurrent awards see each other's XP in the level
+		var profile models.Profile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "preferences").Where("user_id = ?", userID).First(&profile).Error; err != nil {
+			return err
+		}
+		goal = profile.Preferences.DailyXPGoal
+
+		entry = models.XPEntry{UserID: userID, Source: source, Ref: ref, Amount: amount, Day: streaks.Day(now, preferredLocation(profile.Preferences))}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+		if created.Error != nil || created.RowsAffected == 0 {
+			return created.Error
+		}
+		awarded = true
+
+		err := tx.Model(&models.XPEntry{}).Where("user_id = ?", userID).
+			Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.XPEntry{}).Where("user_id = ? AND day = ?", userID, entry.Day).
+			Select("COALESCE(SUM(amount), 0)").Scan(&today).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&profile).Update("level", levelForXP(total)).Error
+	})
+	if err != nil || !awarded {
+		return false, err
+	}
+
+	events.Publish(events.Event{Name: events.XPAwarded, UserID: userID, At: now, Data: map[string]interface{}{
+		"source": source, "amount": amount, "total": total,
+	}})
+	if today >= goal && today-amount < goal {
+		events.Publish(events.Event{Name: events.DailyGoalMet, UserID: userID, At: now, Data: map[string]interface{}{
+			"day": entry.Day, "goal": goal, "xp": today,
+		}})
+	}
+	return true, nil
+}
+
+// awardSessionXP credits a finished session: a lesson, with a bonus for no
+// mistakes, or a practice session, by its right answers. Failures are logged:
+// the session itself is already saved.
+func awardSessionXP(session models.LessonSession) {
+	source, amount := models.XPLesson, lessonXP
+	if session.Correct == session.Answered {
+		amount += perfectLessonXP
+	}
+	if session.Practice {
+		source, amount = models.XPReview, session.Correct
+	}
+	if amount <= 0 {
+		return
+	}
+	if _, err := awardXP(session.UserID, source, fmt.Sprintf("session:%d", session.ID), amount); err != nil {
+		log.Println("Error awarding session XP:", err)
+	}
+}
+
+// awardUpvoteXP credits the author of a thread or post (table "threads" or
+// "posts") with an upvote from voterID. Each voter's upvote counts once, so
+// taking a vote back and casting it again earns nothing more.
+func awardUpvoteXP(table string, id int, voterID uint) {
+	var authorID int
+	err := utils.GormDB.Table(table).Select("user_id").Where("id = ? AND deleted_at IS NULL", id).Limit(1).Scan(&authorID).Error
+	if err != nil {
+		log.Println("Error finding voted author:", err)
+		return
+	}
+	if authorID == 0 || authorID == int(voterID) {
+		return
+	}
+	ref := fmt.Sprintf("%s:%d:%d", strings.TrimSuffix(table, "s"), id, voterID)
+	if _, err := awardXP(authorID, models.XPForumUpvote, ref, forumUpvoteXP); err != nil {
+		log.Println("Error awarding upvote XP:", err)
+	}
+}
+
+// levelForXP is the level all-time XP reaches: level n needs xpForLevel(n)
+func levelForXP(total int) int {
+	level := 0
+	for xpForLevel(level+1) <= total {
+		level++
+	}
+	return level
+}
+
+// xpForLevel is the all-time XP level n needs: 50, 150, 300, 500, ... each
+// level asking 50 XP more than the one before
+func xpForLevel(n int) int {
+	return 25 * n * (n + 1)
+}
+
+// weekStart returns the Monday on or before day
+func weekStart(day string) string {
+	t, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return day
+	}
+	return streaks.AddDays(day, -((int(t.Weekday()) + 6) % 7))
+}
+
+// learnerPreferences returns a learner's preferences, or the defaults if they
+// have no profile
+func learnerPreferences(db *gorm.DB, userID int) (models.Preferences, error) {
+	profile := models.Profile{Preferences: models.DefaultPreferences()}
+	err := db.Select("preferences").Where("user_id = ?", userID).Limit(1).Find(&profile).Error
+	return profile.Preferences, err
+}
+
+// preferredLocation returns the time zone of prefs, or UTC if it can't be loaded
+func preferredLocation(prefs models.Preferences) *time.Location {
+	loc, err := prefs.Location()
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,68 @@
+// events/events.go
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event names, with what their Data holds
+const (
+	StreakExtended  = "streak_extended"  // "current": the new length
+	StreakMilestone = "streak_milestone" // "days": the milestone reached
+	StreakFrozen    = "streak_frozen"    // "days": the missed dates freezes covered
+	StreakLost      = "streak_lost"      // "length": the streak that was broken
+	XPAwarded       = "xp_awarded"       // "source", "amount" and the new "total"
+	DailyGoalMet    = "daily_goal_met"   // "day", the "goal" and the day's "xp"
+	LessonCompleted = "lesson_completed" // "lesson_id" and the session's "correct" and "answered"
+	BadgeEarned     = "badge_earned"     // "badge_id" and "name"
+)
+
+// Event is something that happened to a learner that other parts of the
+// system may react to, such as badges or notifications
+type Event struct {
+	Name   string
+	UserID int
+	Data   map[string]interface{}
+	At     time.Time
+}
+
+// Handler reacts to an event. It runs on the publisher's goroutine, so it
+// should be quick and log its own failures.
+type Handler func(Event)
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string][]Handler{}
+)
+
+// Subscribe calls h for every event published with name
+func Subscribe(name string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[name] = append(handlers[name], h)
+}
+
+// Publish delivers e to its handlers in the order they subscribed. Publish
+// only once what the event reports is committed. A handler that panics is
+// logged and doesn't stop the others.
+func Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	mu.RLock()
+	list := handlers[e.Name]
+	mu.RUnlock()
+
+	for _, h := range list {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Error handling %s event: %v", e.Name, r)
+				}
+			}()
+			h(e)
+		}()
+	}
+}
@@ -0,0 +1,128 @@
+// exercises/exercises.go
+package exercises
+
+import (
+	"Delingo/src/grading"
+	"Delingo/src/models"
+	"Delingo/src/srs"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Exercise types
+const (
+	MultipleChoice = "multiple_choice" // pick the right one of a few choices
+	Translate      = "translate"       // translate the prompt in free text
+	FillBlank      = "fill_blank"      // type or pick the word missing from a sentence
+	WordBank       = "word_bank"       // build the translation from word tiles
+	MatchPairs     = "match_pairs"     // match each word with its translation
+)
+
+var (
+	ErrUnknownType   = errors.New("type must be multiple_choice, translate, fill_blank, word_bank or match_pairs")
+	ErrInvalidAnswer = errors.New("answer doesn't fit the exercise type")
+)
+
+// kind is one type of exercise, decoded from an exercise's content. The
+// content holds the solution, so only public goes to clients before they answer.
+type kind interface {
+	// validate checks content written by an author
+	validate() error
+	// public returns what a learner needs to answer, without the solution
+	public() interface{}
+	// check grades an answer
+	check(answer json.RawMessage) (Result, error)
+	// items returns the words and sentences in language that the exercise
+	// practises; prompt is the exercise's prompt
+	items(prompt, language string) []srs.Item
+}
+
+var kinds = map[string]func() kind{
+	MultipleChoice: func() kind { return &multipleChoice{} },
+	Translate:      func() kind { return &translate{} },
+	FillBlank:      func() kind { return &fillBlank{} },
+	WordBank:       func() kind { return &wordBank{} },
+	MatchPairs:     func() kind { return &matchPairs{} },
+}
+
+// Served is an exercise as sent to a learner
+type Served struct {
+	ID      uint        `json:"id"`
+	Type    string      `json:"type"`
+	Prompt  string      `json:"prompt"`
+	Content interface{} `json:"content"`
+}
+
+// Result is the verdict on an answer. Solution is always given so the learner
+// can see what was expected. Typed answers also get feedback on forgiven slips
+// and the difference from the closest accepted answer.
+type Result struct {
+	Correct  bool              `json:"correct"`
+	Solution interface{}       `json:"solution"`
+	Feedback string            `json:"feedback,omitempty"`
+	Diff     []grading.Segment `json:"diff,omitempty"`
+}
+
+// Validate checks that content is complete and consistent for the type
+func Validate(typ string, content models.JSONMap) error {
+	k, err := decode(typ, content)
+	if err != nil {
+		return err
+	}
+	return k.validate()
+}
+
+// Public returns an exercise without anything that gives its solution away
+func Public(e models.Exercise) (Served, error) {
+	k, err := decode(e.Type, e.Content)
+	if err != nil {
+		return Served{}, err
+	}
+	return Served{ID: e.ID, Type: e.Type, Prompt: e.Prompt, Content: k.public()}, nil
+}
+
+// Check grades a learner's answer to an exercise. It returns ErrInvalidAnswer
+// when the answer isn't shaped for the exercise type.
+func Check(e models.Exercise, answer json.RawMessage) (Result, error) {
+	k, err := decode(e.Type, e.Content)
+	if err != nil {
+		return Result{}, err
+	}
+	return k.check(answer)
+}
+
+// Items returns the words and sentences of a course's target language that
+// answering an exercise practises, for spaced repetition
+func Items(e models.Exercise, language string) ([]srs.Item, error) {
+	k, err := decode(e.Type, e.Content)
+	if err != nil {
+		return nil, err
+	}
+	return k.items(e.Prompt, language), nil
+}
+
+// decode reads content into the kind for typ
+func decode(typ string, content models.JSONMap) (kind, error) {
+	newKind, ok := kinds[typ]
+	if !ok {
+		return nil, ErrUnknownType
+	}
+	k := newKind()
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, fmt.Errorf("content doesn't fit a %s exercise: %w", typ, err)
+	}
+	return k, nil
+}
+
+// decodeAnswer reads a learner's answer into v
+func decodeAnswer(answer json.RawMessage, v interface{}) error {
+	if json.Unmarshal(answer, v) != nil {
+		return ErrInvalidAnswer
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package exercises
+
+import (
+	"Delingo/src/models"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// content builds exercise content from JSON, as it is stored
+func content(t *testing.T, raw string) models.JSONMap {
+	t.Helper()
+	var m models.JSONMap
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("bad test content %s: %v", raw, err)
+	}
+	return m
+}
+
+const (
+	choiceContent    = `{"choices": ["gato", "perro", "pez"], "answer": 0}`
+	translateContent = `{"answers": ["I am a cat", "I'm a cat"], "language": "en"}`
+	typedBlank       = `{"sentence": "Yo ___ un gato", "answers": ["soy"], "language": "es"}`
+	pickedBlank      = `{"sentence": "Yo ___ un gato", "answers": ["soy"], "choices": ["soy", "eres", "es"]}`
+	wordBankContent  = `{"answers": [["I", "am", "a", "cat"], ["a", "cat", "I", "am"]], "distractors": ["is"]}`
+	pairsContent     = `{"pairs": [["cat", "gato"], ["dog", "perro"]]}`
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     string
+		content string
+		ok      bool
+	}{
+		{"unknown type", "essay", `{}`, false},
+		{"content of the wrong shape", MultipleChoice, `{"choices": "gato"}`, false},
+
+		{"multiple choice", MultipleChoice, choiceContent, true},
+		{"one choice", MultipleChoice, `{"choices": ["gato"], "answer": 0}`, false},
+		{"answer out of range", MultipleChoice, `{"choices": ["gato", "perro"], "answer": 2}`, false},
+		{"negative answer", MultipleChoice, `{"choices": ["gato", "perro"], "answer": -1}`, false},
+
+		{"translate", Translate, translateContent, true},
+		{"no answers", Translate, `{"answers": []}`, false},
+		{"blank first answer", Translate, `{"answers": [" "]}`, false},
+
+		{"typed blank", FillBlank, typedBlank, true},
+		{"picked blank", FillBlank, pickedBlank, true},
+		{"no gap", FillBlank, `{"sentence": "Yo soy un gato", "answers": ["soy"]}`, false},
+		{"two gaps", FillBlank, `{"sentence": "___ ___ un gato", "answers": ["soy"]}`, false},
+		{"choices without the answer", FillBlank, `{"sentence": "Yo ___", "answers": ["soy"], "choices": ["eres", "es"]}`, false},
+
+		{"word bank", WordBank, wordBankContent, true},
+		{"no order", WordBank, `{"answers": []}`, false},
+		{"orders of other words", WordBank, `{"answers": [["I", "am"], ["I", "is"]]}`, false},
+
+		{"match pairs", MatchPairs, pairsContent, true},
+		{"one pair", MatchPairs, `{"pairs": [["cat", "gato"]]}`, false},
+		{"empty word", MatchPairs, `{"pairs": [["cat", "gato"], ["dog", ""]]}`, false},
+		{"repeated left word", MatchPairs, `{"pairs": [["cat", "gato"], ["cat", "perro"]]}`, false},
+		{"repeated right word", MatchPairs, `{"pairs": [["cat", "gato"], ["kitty", "gato"]]}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.typ, content(t, tt.content))
+			if (err == nil) != tt.ok {
+				t.Errorf("Validate(%s, %s) = %v, want ok %v", tt.typ, tt.content, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     string
+		content string
+		answer  string
+		correct bool
+		invalid bool
+	}{
+		{"right choice", MultipleChoice, choiceContent, `{"choice": 0}`, true, false},
+		{"wrong choice", MultipleChoice, choiceContent, `{"choice": 2}`, false, false},
+		{"no choice", MultipleChoice, choiceContent, `{}`, false, true},
+		{"choice of the wrong type", MultipleChoice, choiceContent, `{"choice": "gato"}`, false, true},
+
+		{"exact translation", Translate, translateContent, `{"text": "I am a cat"}`, true, false},
+		{"other accepted translation", Translate, translateContent, `{"text": "i'm a cat!"}`, true, false},
+		{"translation with a typo", Translate, translateContent, `{"text": "I am a cst"}`, true, false},
+		{"wrong translation", Translate, translateContent, `{"text": "I am a dog"}`, false, false},
+		{"answer not an object", Translate, translateContent, `"I am a cat"`, false, true},
+
+		{"typed blank", FillBlank, typedBlank, `{"text": "soy"}`, true, false},
+		{"typed blank, wrong", FillBlank, typedBlank, `{"text": "es"}`, false, false},
+		{"picked blank", FillBlank, pickedBlank, `{"text": " Soy "}`, true, false},
+		{"picked blank, no typos forgiven", FillBlank, pickedBlank, `{"text": "eres"}`, false, false},
+
+		{"first order", WordBank, wordBankContent, `{"words": ["I", "am", "a", "cat"]}`, true, false},
+		{"other accepted order", WordBank, wordBankContent, `{"words": ["a", "cat", "I", "am"]}`, true, false},
+		{"with a distractor", WordBank, wordBankContent, `{"words": ["I", "is", "a", "cat"]}`, false, false},
+		{"words missing", WordBank, wordBankContent, `{"words": ["I", "am"]}`, false, false},
+
+		{"all pairs", MatchPairs, pairsContent, `{"pairs": [["dog", "perro"], ["cat", "gato"]]}`, true, false},
+		{"pairs swapped", MatchPairs, pairsContent, `{"pairs": [["cat", "perro"], ["dog", "gato"]]}`, false, false},
+		{"a pair missing", MatchPairs, pairsContent, `{"pairs": [["cat", "gato"]]}`, false, false},
+		{"a pair repeated", MatchPairs, pairsContent, `{"pairs": [["cat", "gato"], ["cat", "gato"]]}`, false, false},
+		{"unknown word paired with nothing", MatchPairs, pairsContent, `{"pairs": [["cat", "gato"], ["zzz", ""]]}`, false, false},
+		{"an extra pair", MatchPairs, pairsContent, `{"pairs": [["cat", "gato"], ["dog", "perro"], ["zzz", "x"]]}`, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := models.Exercise{Type: tt.typ, Content: content(t, tt.content)}
+			result, err := Check(e, json.RawMessage(tt.answer))
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidAnswer) {
+					t.Errorf("Check(%s) error = %v, want ErrInvalidAnswer", tt.answer, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check(%s): %v", tt.answer, err)
+			}
+			if result.Correct != tt.correct {
+				t.Errorf("Check(%s).Correct = %v, want %v", tt.answer, result.Correct, tt.correct)
+			}
+			if result.Solution == nil {
+				t.Error("Check gave no solution")
+			}
+		})
+	}
+}
+
+func TestCheckMatchPairsReportsWrongPairs(t *testing.T) {
+	e := models.Exercise{Type: MatchPairs, Content: content(t, pairsContent)}
+	result, err := Check(e, json.RawMessage(`{"pairs": [["cat", "gato"], ["zzz", ""]]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := result.Solution.(map[string]interface{})["wrong_pairs"]
+	if want := [][2]string{{"zzz", ""}}; !reflect.DeepEqual(wrong, want) {
+		t.Errorf("wrong_pairs = %v, want %v", wrong, want)
+	}
+}
+
+// Public gives what's needed to answer and nothing that gives the solution away
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     string
+		content string
+		want    map[string][]string // content keys and their words, in any order
+		hidden  []string            // keys that must not be served
+	}{
+		{"multiple choice", MultipleChoice, choiceContent,
+			map[string][]string{"choices": {"gato", "perro", "pez"}}, []string{"answer"}},
+		{"translate", Translate, translateContent,
+			map[string][]string{}, []string{"answers", "language"}},
+		{"typed blank", FillBlank, typedBlank,
+			map[string][]string{"sentence": {"Yo ___ un gato"}}, []string{"answers", "choices"}},
+		{"picked blank", FillBlank, pickedBlank,
+			map[string][]string{"sentence": {"Yo ___ un gato"}, "choices": {"soy", "eres", "es"}}, []string{"answers"}},
+		{"word bank", WordBank, wordBankContent,
+			map[string][]string{"tiles": {"I", "am", "a", "cat", "is"}}, []string{"answers", "distractors"}},
+		{"match pairs", MatchPairs, pairsContent,
+			map[string][]string{"left": {"cat", "dog"}, "right": {"gato", "perro"}}, []string{"pairs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := models.Exercise{ID: 7, Type: tt.typ, Prompt: "prompt", Content: content(t, tt.content)}
+			served, err := Public(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if served.ID != 7 || served.Type != tt.typ || served.Prompt != "prompt" {
+				t.Errorf("served %+v, want the exercise's ID, type and prompt", served)
+			}
+
+			// Round-trip through JSON, as clients see it
+			data, err := json.Marshal(served.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("served content %s, want only the keys of %v", data, tt.want)
+			}
+			for key, words := range tt.want {
+				if gotWords := wordsOf(got[key]); !reflect.DeepEqual(gotWords, sortedCopy(words)) {
+					t.Errorf("%s = %v, want %v in any order", key, gotWords, words)
+				}
+			}
+			for _, key := range tt.hidden {
+				if _, ok := got[key]; ok {
+					t.Errorf("served content %s gives away %q", data, key)
+				}
+			}
+		})
+	}
+}
+
+// wordsOf sorts a served string or list of strings, for comparison
+func wordsOf(v interface{}) []string {
+	var words []string
+	switch v := v.(type) {
+	case string:
+		words = []string{v}
+	case []interface{}:
+		for _, w := range v {
+			s, _ := w.(string)
+			words = append(words, s)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
+
+func TestItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      string
+		prompt   string
+		content  string
+		language string
+		want     []string // kind:text
+	}{
+		{"multiple choice", MultipleChoice, "cat", choiceContent, "es", []string{"word:gato"}},
+		{"translating into the language", Translate, "Soy un gato", translateContent, "en", []string{"sentence:I am a cat"}},
+		{"translating out of the language", Translate, "Soy un gato", translateContent, "es", []string{"sentence:Soy un gato"}},
+		{"fill blank", FillBlank, "", typedBlank, "es", []string{"word:soy"}},
+		{"word bank", WordBank, "", wordBankContent, "en", []string{"sentence:I am a cat"}},
+		{"match pairs", MatchPairs, "", pairsContent, "es", []string{"word:gato", "word:perro"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := models.Exercise{Type: tt.typ, Prompt: tt.prompt, Content: content(t, tt.content)}
+			items, err := Items(e, tt.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, item.Kind+":"+item.Text)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
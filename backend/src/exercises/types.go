@@ -0,0 +1,315 @@
+// exercises/types.go
+package exercises
+
+import (
+	"Delingo/src/grading"
+	"Delingo/src/models"
+	"Delingo/src/srs"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// blank marks the gap in a fill_blank sentence
+const blank = "___"
+
+// multipleChoice content: {"choices": ["gato", "perro", "pez"], "answer": 0}
+// Answer: {"choice": 0}
+type multipleChoice struct {
+	Choices []string `json:"choices"`
+	Answer  int      `json:"answer"` // index into Choices
+}
+
+func (m *multipleChoice) validate() error {
+	if len(m.Choices) < 2 {
+		return errors.New("a multiple_choice exercise needs at least two choices")
+	}
+	if m.Answer < 0 || m.Answer >= len(m.Choices) {
+		return errors.New("answer must be the index of one of the choices")
+	}
+	return nil
+}
+
+func (m *multipleChoice) public() interface{} {
+	return map[string]interface{}{"choices": m.Choices}
+}
+
+func (m *multipleChoice) check(answer json.RawMessage) (Result, error) {
+	var a struct {
+		Choice *int `json:"choice"`
+	}
+	if err := decodeAnswer(answer, &a); err != nil || a.Choice == nil {
+		return Result{}, ErrInvalidAnswer
+	}
+	return Result{
+		Correct:  *a.Choice == m.Answer,
+		Solution: map[string]interface{}{"choice": m.Answer, "text": m.Choices[m.Answer]},
+	}, nil
+}
+
+func (m *multipleChoice) items(prompt, language string) []srs.Item {
+	return []srs.Item{itemOf(m.Choices[m.Answer])}
+}
+
+// translate content: {"answers": ["I am a cat", "I'm a cat"], "language": "en"},
+// the first answer being the one shown as the solution. The prompt is the
+// sentence to translate; language is that of the answers, for its contractions
+// and synonyms.
+// Answer: {"text": "I'm a cat"}
+type translate struct {
+	Answers  []string `json:"answers"`
+	Language string   `json:"language"`
+}
+
+func (t *translate) validate() error {
+	if len(t.Answers) == 0 || strings.TrimSpace(t.Answers[0]) == "" {
+		return errors.New("a translate exercise needs at least one accepted answer")
+	}
+	return nil
+}
+
+func (t *translate) public() interface{} {
+	return map[string]interface{}{}
+}
+
+func (t *translate) check(answer json.RawMessage) (Result, error) {
+	var a struct {
+		Text string `json:"text"`
+	}
+	if err := decodeAnswer(answer, &a); err != nil {
+		return Result{}, err
+	}
+	return gradedResult(grading.Grade(a.Text, t.Answers, t.Language), map[string]interface{}{"text": t.Answers[0]}), nil
+}
+
+// A translation practises the sentence in the language being learned, whichever
+// way it goes
+func (t *translate) items(prompt, language string) []srs.Item {
+	if sameLanguage(t.Language, language) {
+		return []srs.Item{itemOf(t.Answers[0])}
+	}
+	return []srs.Item{itemOf(prompt)}
+}
+
+// fillBlank content: {"sentence": "Yo ___ un gato", "answers": ["soy"],
+// "choices": ["soy", "eres", "es"], "language": "es"}. Without choices the
+// learner types the word, and is forgiven slips as in translate.
+// Answer: {"text": "soy"}
+type fillBlank struct {
+	Sentence string   `json:"sentence"`
+	Answers  []string `json:"answers"`
+	Choices  []string `json:"choices"`
+	Language string   `json:"language"`
+}
+
+func (f *fillBlank) validate() error {
+	if strings.Count(f.Sentence, blank) != 1 {
+		return errors.New("sentence must contain exactly one " + blank)
+	}
+	if len(f.Answers) == 0 || strings.TrimSpace(f.Answers[0]) == "" {
+		return errors.New("a fill_blank exercise needs at least one accepted answer")
+	}
+	if len(f.Choices) > 0 && !matchesAny(f.Answers[0], f.Choices) {
+		return errors.New("choices must include the answer")
+	}
+	return nil
+}
+
+func (f *fillBlank) public() interface{} {
+	content := map[string]interface{}{"sentence": f.Sentence}
+	if len(f.Choices) > 0 {
+		content["choices"] = shuffled(f.Choices)
+	}
+	return content
+}
+
+func (f *fillBlank) check(answer json.RawMessage) (Result, error) {
+	var a struct {
+		Text string `json:"text"`
+	}
+	if err := decodeAnswer(answer, &a); err != nil {
+		return Result{}, err
+	}
+	solution := map[string]interface{}{
+		"text":     f.Answers[0],
+		"sentence": strings.Replace(f.Sentence, blank, f.Answers[0], 1),
+	}
+	if len(f.Choices) > 0 {
+		return Result{Correct: matchesAny(a.Text, f.Answers), Solution: solution}, nil
+	}
+	return gradedResult(grading.Grade(a.Text, f.Answers, f.Language), solution), nil
+}
+
+func (f *fillBlank) items(prompt, language string) []srs.Item {
+	return []srs.Item{itemOf(f.Answers[0])}
+}
+
+// wordBank content: {"answers": [["I", "am", "a", "cat"]], "distractors": ["is"]}.
+// Each answer is an accepted order; the tiles are the first answer's words and
+// the distractors, shuffled.
+// Answer: {"words": ["I", "am", "a", "cat"]}
+type wordBank struct {
+	Answers     [][]string `json:"answers"`
+	Distractors []string   `json:"distractors"`
+}
+
+func (w *wordBank) validate() error {
+	if len(w.Answers) == 0 || len(w.Answers[0]) == 0 {
+		return errors.New("a word_bank exercise needs at least one accepted order of words")
+	}
+	// Every accepted order must be buildable from the same tiles
+	tiles := strings.Join(sortedCopy(w.Answers[0]), "\x00")
+	for _, order := range w.Answers[1:] {
+		if strings.Join(sortedCopy(order), "\x00") != tiles {
+			return errors.New("every accepted order must use the same words")
+		}
+	}
+	return nil
+}
+
+func (w *wordBank) public() interface{} {
+	tiles := append(append([]string{}, w.Answers[0]...), w.Distractors...)
+	return map[string]interface{}{"tiles": shuffled(tiles)}
+}
+
+func (w *wordBank) check(answer json.RawMessage) (Result, error) {
+	var a struct {
+		Words []string `json:"words"`
+	}
+	if err := decodeAnswer(answer, &a); err != nil {
+		return Result{}, err
+	}
+	correct := false
+	for _, order := range w.Answers {
+		if matchesAny(strings.Join(a.Words, " "), []string{strings.Join(order, " ")}) {
+			correct = true
+			break
+		}
+	}
+	return Result{
+		Correct:  correct,
+		Solution: map[string]interface{}{"words": w.Answers[0]},
+	}, nil
+}
+
+func (w *wordBank) items(prompt, language string) []srs.Item {
+	return []srs.Item{itemOf(strings.Join(w.Answers[0], " "))}
+}
+
+// matchPairs content: {"pairs": [["cat", "gato"], ["dog", "perro"]]}, each
+// pair a word and its translation in the language being learned
+// Answer: {"pairs": [["cat", "gato"], ["dog", "perro"]]}, in any order
+type matchPairs struct {
+	Pairs [][2]string `json:"pairs"`
+}
+
+func (m *matchPairs) validate() error {
+	if len(m.Pairs) < 2 {
+		return errors.New("a match_pairs exercise needs at least two pairs")
+	}
+	left, right := map[string]bool{}, map[string]bool{}
+	for _, p := range m.Pairs {
+		if p[0] == "" || p[1] == "" || left[p[0]] || right[p[1]] {
+			return errors.New("pairs must not be empty or repeat a word on either side")
+		}
+		left[p[0]], right[p[1]] = true, true
+	}
+	return nil
+}
+
+func (m *matchPairs) public() interface{} {
+	var left, right []string
+	for _, p := range m.Pairs {
+		left = append(left, p[0])
+		right = append(right, p[1])
+	}
+	return map[string]interface{}{"left": shuffled(left), "right": shuffled(right)}
+}
+
+func (m *matchPairs) check(answer json.RawMessage) (Result, error) {
+	var a struct {
+		Pairs [][2]string `json:"pairs"`
+	}
+	if err := decodeAnswer(answer, &a); err != nil {
+		return Result{}, err
+	}
+	want := map[string]string{}
+	for _, p := range m.Pairs {
+		want[p[0]] = p[1]
+	}
+
+	// Every pair must be matched, and matched right
+	wrong := [][2]string{}
+	matched := map[string]bool{}
+	for _, p := range a.Pairs {
+		w, ok := want[p[0]]
+		if !ok || w != p[1] || matched[p[0]] {
+			wrong = append(wrong, p)
+			continue
+		}
+		matched[p[0]] = true
+	}
+	return Result{
+		Correct:  len(wrong) == 0 && len(matched) == len(m.Pairs),
+		Solution: map[string]interface{}{"pairs": m.Pairs, "wrong_pairs": wrong},
+	}, nil
+}
+
+func (m *matchPairs) items(prompt, language string) []srs.Item {
+	var items []srs.Item
+	for _, p := range m.Pairs {
+		items = append(items, itemOf(p[1]))
+	}
+	return items
+}
+
+// gradedResult turns the verdict on a typed answer into a Result
+func gradedResult(graded grading.Result, solution interface{}) Result {
+	return Result{Correct: graded.Correct, Solution: solution, Feedback: graded.Feedback, Diff: graded.Diff}
+}
+
+// itemOf makes a review item of text: a word, or a sentence if it has spaces
+func itemOf(text string) srs.Item {
+	if len(strings.Fields(text)) > 1 {
+		return srs.Item{Kind: models.ReviewSentence, Text: text}
+	}
+	return srs.Item{Kind: models.ReviewWord, Text: text}
+}
+
+// sameLanguage reports whether two language codes share a base language, so
+// "pt-BR" and "pt" are the same
+func sameLanguage(a, b string) bool {
+	base := func(code string) string {
+		base, _, _ := strings.Cut(strings.ToLower(code), "-")
+		return base
+	}
+	return base(a) == base(b)
+}
+
+// matchesAny reports whether text is one of the accepted answers, ignoring
+// case and extra spaces
+func matchesAny(text string, accepted []string) bool {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, a := range accepted {
+		if strings.EqualFold(text, strings.Join(strings.Fields(a), " ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// shuffled returns a shuffled copy of words
+func shuffled(words []string) []string {
+	out := append([]string{}, words...)
+	rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out
+}
+
+// sortedCopy returns words in sorted order, leaving words as it was
+func sortedCopy(words []string) []string {
+	out := append([]string{}, words...)
+	sort.Strings(out)
+	return out
+}
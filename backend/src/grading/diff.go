@@ -0,0 +1,80 @@
+// grading/diff.go
+package grading
+
+import "strings"
+
+// Diff operations
+const (
+	Equal   = "equal"   // in both the response and the answer
+	Missing = "missing" // in the answer but not typed
+	Extra   = "extra"   // typed but not in the answer
+)
+
+// maxDiffLength caps the runes compared, as the diff takes time and memory
+// in the product of the two lengths
+const maxDiffLength = 500
+
+// Segment is a run of text the response and answer agree or differ on
+type Segment struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff highlights, character by character, how response differs from answer.
+// Case is ignored when matching but each segment keeps the text as written.
+func Diff(response, answer string) []Segment {
+	a, b := []rune(response), []rune(answer)
+	if len(a) > maxDiffLength || len(b) > maxDiffLength {
+		return []Segment{{Op: Extra, Text: response}, {Op: Missing, Text: answer}}
+	}
+	la, lb := []rune(strings.ToLower(response)), []rune(strings.ToLower(answer))
+	if len(la) != len(a) || len(lb) != len(b) {
+		la, lb = a, b
+	}
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if la[i] == lb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var segments []Segment
+	add := func(op string, r rune) {
+		if n := len(segments); n > 0 && segments[n-1].Op == op {
+			segments[n-1].Text += string(r)
+			return
+		}
+		segments = append(segments, Segment{Op: op, Text: string(r)})
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case la[i] == lb[j]:
+			add(Equal, b[j])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(Extra, a[i])
+			i++
+		default:
+			add(Missing, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add(Extra, a[i])
+	}
+	for ; j < len(b); j++ {
+		add(Missing, b[j])
+	}
+	return segments
+}
@@ -0,0 +1,104 @@
+package grading
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name             string
+		response, answer string
+		want             []Segment
+	}{
+		{
+			name:     "equal",
+			response: "hola",
+			answer:   "hola",
+			want:     []Segment{{Equal, "hola"}},
+		},
+		{
+			name:     "case is ignored, matched text is the answer's",
+			response: "HOLA",
+			answer:   "hola",
+			want:     []Segment{{Equal, "hola"}},
+		},
+		{
+			name:     "missing letter",
+			response: "helo",
+			answer:   "hello",
+			want:     []Segment{{Equal, "hel"}, {Missing, "l"}, {Equal, "o"}},
+		},
+		{
+			name:     "extra letter",
+			response: "helllo",
+			answer:   "hello",
+			want:     []Segment{{Equal, "hell"}, {Extra, "l"}, {Equal, "o"}},
+		},
+		{
+			name:     "substitution",
+			response: "cat",
+			answer:   "car",
+			want:     []Segment{{Equal, "ca"}, {Extra, "t"}, {Missing, "r"}},
+		},
+		{
+			name:     "missed accent",
+			response: "cafe",
+			answer:   "café",
+			want:     []Segment{{Equal, "caf"}, {Extra, "e"}, {Missing, "é"}},
+		},
+		{
+			name:     "missing word",
+			response: "I am student",
+			answer:   "I am a student",
+			want:     []Segment{{Equal, "I am "}, {Missing, "a "}, {Equal, "student"}},
+		},
+		{
+			name:     "empty response",
+			response: "",
+			answer:   "hi",
+			want:     []Segment{{Missing, "hi"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.response, tt.answer); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff(%q, %q) = %v, want %v", tt.response, tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
+// Equal and missing runs rebuild the answer as written; equal and extra runs
+// the response, but for case
+func TestDiffRebuildsBothTexts(t *testing.T) {
+	pairs := [][2]string{
+		{"the dag is big", "The dog is big."},
+		{"¿Dónde estás?", "dónde está"},
+		{"xyz", "abc"},
+	}
+	for _, p := range pairs {
+		var response, answer strings.Builder
+		for _, s := range Diff(p[0], p[1]) {
+			if s.Op != Missing {
+				response.WriteString(s.Text)
+			}
+			if s.Op != Extra {
+				answer.WriteString(s.Text)
+			}
+		}
+		if !strings.EqualFold(response.String(), p[0]) || answer.String() != p[1] {
+			t.Errorf("Diff(%q, %q) rebuilds %q and %q", p[0], p[1], response.String(), answer.String())
+		}
+	}
+}
+
+func TestDiffTooLong(t *testing.T) {
+	long := strings.Repeat("a", maxDiffLength+1)
+	want := []Segment{{Extra, long}, {Missing, "a"}}
+	if got := Diff(long, "a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff of an over-long response = %d segments, want the whole texts", len(got))
+	}
+}
@@ -0,0 +1,174 @@
+// grading/grading.go
+package grading
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Verdicts, from best to worst. Accent slips and typos still count as correct.
+const (
+	Exact  = "exact"  // matches an accepted answer once case, punctuation and spacing are ignored
+	Accent = "accent" // matches except for diacritics
+	Typo   = "typo"   // one character away from an accepted answer
+	Wrong  = "wrong"
+)
+
+// minTypoLength is the shortest answer a typo is forgiven in; in shorter
+// words one wrong letter too often makes another word
+const minTypoLength = 4
+
+// Result is the verdict on a free-text answer
+type Result struct {
+	Verdict  string    `json:"verdict"`
+	Correct  bool      `json:"correct"`
+	Matched  string    `json:"matched"` // the accepted answer closest to the response, as written
+	Feedback string    `json:"feedback,omitempty"`
+	Diff     []Segment `json:"diff,omitempty"` // how the response differs from Matched, unless exact
+}
+
+// Grade compares a learner's response with the accepted answers, forgiving
+// what a teacher would: case, punctuation, spacing, contractions and synonyms
+// of language (a language code such as "en", or "" for none), a missed accent
+// and, in longer answers, a one-character typo.
+func Grade(response string, accepted []string, language string) Result {
+	best := Result{Verdict: Wrong}
+	bestDistance := -1
+	typed := Normalize(response, language)
+	typedFolded := foldDiacritics(typed)
+
+	for _, answer := range accepted {
+		want := Normalize(answer, language)
+		wantFolded := foldDiacritics(want)
+
+		var verdict string
+		distance := editDistance(typedFolded, wantFolded)
+		switch {
+		case typed == want:
+			verdict = Exact
+		case typedFolded == wantFolded:
+			verdict = Accent
+		case distance == 1 && utf8.RuneCountInString(wantFolded) >= minTypoLength:
+			verdict = Typo
+		default:
+			verdict = Wrong
+		}
+
+		if rank(verdict) < rank(best.Verdict) || (verdict == best.Verdict && (bestDistance < 0 || distance < bestDistance)) {
+			best = Result{Verdict: verdict, Matched: answer}
+			bestDistance = distance
+		}
+	}
+
+	best.Correct = best.Verdict != Wrong
+	switch best.Verdict {
+	case Accent:
+		best.Feedback = "You missed an accent"
+	case Typo:
+		best.Feedback = "You have a typo"
+	}
+	if best.Verdict != Exact && best.Matched != "" {
+		best.Diff = Diff(strings.TrimSpace(response), best.Matched)
+	}
+	return best
+}
+
+// rank orders verdicts from best (0) to worst
+func rank(verdict string) int {
+	switch verdict {
+	case Exact:
+		return 0
+	case Accent:
+		return 1
+	case Typo:
+		return 2
+	}
+	return 3
+}
+
+// Normalize reduces text to the form answers are compared in: lower case,
+// contractions of language expanded, punctuation dropped, synonyms replaced
+// with one word of their group and words separated by single spaces.
+// Diacritics are kept.
+func Normalize(text, language string) string {
+	text = norm.NFC.String(strings.ToLower(text))
+	text = strings.NewReplacer("’", "'", "‘", "'", "`", "'").Replace(text)
+	lists := languages[baseLanguage(language)]
+
+	// Contractions first, while their apostrophes are still there
+	words := strings.Fields(text)
+	var expanded []string
+	for _, word := range words {
+		core := strings.TrimFunc(word, isPunctuation)
+		if full, ok := lists.contractions[core]; ok {
+			expanded = append(expanded, full)
+			continue
+		}
+		expanded = append(expanded, word)
+	}
+	text = strings.Join(expanded, " ")
+
+	// Punctuation separates words like a space would
+	text = strings.Map(func(r rune) rune {
+		if isPunctuation(r) {
+			return ' '
+		}
+		return r
+	}, text)
+
+	words = strings.Fields(text)
+	for i, word := range words {
+		if canonical, ok := lists.synonyms[word]; ok {
+			words[i] = canonical
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// isPunctuation reports whether r is punctuation or a symbol such as ¿ or «
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// foldDiacritics removes accents and other combining marks: "café" becomes "cafe"
+func foldDiacritics(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return folded
+}
+
+// editDistance counts the single-character edits that turn a into b:
+// insertions, deletions, substitutions and swaps of neighbouring characters
+// (Levenshtein distance with transpositions, as optimal string alignment)
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	older := make([]int, len(br)+1) // row i-2, for transpositions
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				cur[j] = min(cur[j], older[j-2]+1)
+			}
+		}
+		older, prev, cur = prev, cur, older
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,123 @@
+package grading
+
+import "testing"
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		accepted []string
+		language string
+		verdict  string
+		matched  string
+	}{
+		{"exact", "I am a student", []string{"I am a student"}, "en", Exact, "I am a student"},
+		{"case, punctuation and spacing", "  i AM a   student!! ", []string{"I am a student."}, "en", Exact, "I am a student."},
+		{"contraction before punctuation", "I'm a student.", []string{"I am a student"}, "en", Exact, "I am a student"},
+		{"curly apostrophe contraction", "I’m a student", []string{"I am a student"}, "en", Exact, "I am a student"},
+		{"contraction in the answer", "I do not know", []string{"I don't know"}, "en", Exact, "I don't know"},
+		{"synonym", "my favourite colour", []string{"my favorite color"}, "en", Exact, "my favorite color"},
+		{"no lists without a language", "don't go", []string{"do not go"}, "", Wrong, "do not go"},
+		{"regional language code", "don't go", []string{"do not go"}, "en-GB", Exact, "do not go"},
+		{"missed accent", "cafe", []string{"café"}, "fr", Accent, "café"},
+		{"accent slip beats a typo", "esta", []string{"estar", "está"}, "es", Accent, "está"},
+		{"substitution", "helko", []string{"hello"}, "en", Typo, "hello"},
+		{"insertion", "helllo", []string{"hello"}, "en", Typo, "hello"},
+		{"deletion", "helo", []string{"hello"}, "en", Typo, "hello"},
+		{"transposition", "hlelo", []string{"hello"}, "en", Typo, "hello"},
+		{"accent and a typo", "cafa", []string{"cafè"}, "fr", Typo, "cafè"},
+		{"typo in a short word", "cat", []string{"car"}, "en", Wrong, "car"},
+		{"typo at minTypoLength", "bood", []string{"book"}, "en", Typo, "book"},
+		{"two edits", "hepko", []string{"hello"}, "en", Wrong, "hello"},
+		{"closest wrong answer", "the cow", []string{"a cat", "the dog"}, "en", Wrong, "the dog"},
+		{"exact beats a typo", "house", []string{"horse", "house"}, "en", Exact, "house"},
+		{"nothing accepted", "hello", nil, "en", Wrong, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Grade(tt.response, tt.accepted, tt.language)
+			if got.Verdict != tt.verdict || got.Matched != tt.matched {
+				t.Errorf("Grade(%q) = %s matching %q, want %s matching %q",
+					tt.response, got.Verdict, got.Matched, tt.verdict, tt.matched)
+			}
+			if got.Correct != (tt.verdict != Wrong) {
+				t.Errorf("Correct = %v for verdict %s", got.Correct, got.Verdict)
+			}
+			if (got.Diff == nil) != (tt.verdict == Exact || tt.matched == "") {
+				t.Errorf("Diff = %v for verdict %s", got.Diff, got.Verdict)
+			}
+		})
+	}
+}
+
+func TestGradeFeedback(t *testing.T) {
+	if got := Grade("cafe", []string{"café"}, "fr").Feedback; got != "You missed an accent" {
+		t.Errorf("accent feedback = %q", got)
+	}
+	if got := Grade("helo", []string{"hello"}, "en").Feedback; got != "You have a typo" {
+		t.Errorf("typo feedback = %q", got)
+	}
+	if got := Grade("hello", []string{"hello"}, "en").Feedback; got != "" {
+		t.Errorf("exact feedback = %q, want none", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text, language, want string
+	}{
+		{"Hello,   World!", "", "hello world"},
+		{"Don't stop.", "en", "do not stop"},
+		{"“It's okay,” she said.", "en", "it is okay she said"},
+		{"rock'n'roll", "en", "rock n roll"},
+		{"¿Dónde está el coche?", "es", "dónde está el coche"},
+		{"Voy al cine", "es", "voy a el cine"},
+		{"Ich bin MÜDE", "de", "ich bin müde"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.text, tt.language); got != tt.want {
+			t.Errorf("Normalize(%q, %q) = %q, want %q", tt.text, tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestFoldDiacritics(t *testing.T) {
+	tests := map[string]string{
+		"café":     "cafe",
+		"niño":     "nino",
+		"über":     "uber",
+		"ação":     "acao",
+		"no marks": "no marks",
+	}
+	for in, want := range tests {
+		if got := foldDiacritics(in); got != want {
+			t.Errorf("foldDiacritics(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"hello", "hello", 0},
+		{"hello", "helko", 1}, // substitution
+		{"hello", "helo", 1},  // deletion
+		{"helo", "hello", 1},  // insertion
+		{"hello", "hlelo", 1}, // transposition
+		{"ab", "ba", 1},
+		{"abc", "ca", 3}, // optimal string alignment doesn't edit a swapped pair again
+		{"kitten", "sitting", 3},
+		{"straße", "strasse", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// grading/languages.go
+package grading
+
+import "strings"
+
+// wordLists are what a language lets learners write more than one way
+type wordLists struct {
+	contractions map[string]string // contraction → the words it stands for
+	synonyms     map[string]string // word → the first word of its group
+}
+
+// languages holds the lists of each language, by base language code
+var languages = map[string]wordLists{
+	"en": newWordLists(
+		map[string]string{
+			"i'm": "i am", "you're": "you are", "he's": "he is", "she's": "she is", "it's": "it is",
+			"we're": "we are", "they're": "they are", "that's": "that is", "there's": "there is",
+			"what's": "what is", "where's": "where is", "who's": "who is",
+			"i've": "i have", "you've": "you have", "we've": "we have", "they've": "they have",
+			"i'll": "i will", "you'll": "you will", "he'll": "he will", "she'll": "she will",
+			"we'll": "we will", "they'll": "they will", "i'd": "i would", "you'd": "you would",
+			"isn't": "is not", "aren't": "are not", "wasn't": "was not", "weren't": "were not",
+			"don't": "do not", "doesn't": "does not", "didn't": "did not",
+			"haven't": "have not", "hasn't": "has not", "hadn't": "had not",
+			"won't": "will not", "wouldn't": "would not", "can't": "can not", "cannot": "can not",
+			"couldn't": "could not", "shouldn't": "should not", "let's": "let us",
+		},
+		[][]string{
+			{"okay", "ok"},
+			{"mom", "mum"},
+			{"color", "colour"},
+			{"favorite", "favourite"},
+			{"gray", "grey"},
+			{"theater", "theatre"},
+			{"center", "centre"},
+		},
+	),
+	"es": newWordLists(
+		map[string]string{"al": "a el", "del": "de el"},
+		[][]string{
+			{"coche", "carro", "auto"},
+			{"ordenador", "computadora", "computador"},
+			{"móvil", "celular"},
+			{"zumo", "jugo"},
+		},
+	),
+	"pt": newWordLists(
+		map[string]string{
+			"do": "de o", "da": "de a", "dos": "de os", "das": "de as",
+			"no": "em o", "na": "em a", "nos": "em os", "nas": "em as",
+			"ao": "a o", "à": "a a",
+		},
+		[][]string{
+			{"ônibus", "autocarro"},
+			{"celular", "telemóvel"},
+			{"trem", "comboio"},
+		},
+	),
+	"de": newWordLists(
+		map[string]string{
+			"am": "an dem", "ans": "an das", "beim": "bei dem", "im": "in dem", "ins": "in das",
+			"vom": "von dem", "zum": "zu dem", "zur": "zu der",
+		},
+		[][]string{
+			{"samstag", "sonnabend"},
+			{"tschüss", "tschüs"},
+		},
+	),
+	"fr": newWordLists(
+		map[string]string{"au": "à le", "aux": "à les", "du": "de le", "des": "de les"},
+		[][]string{
+			{"voiture", "auto"},
+			{"portable", "mobile"},
+		},
+	),
+}
+
+// newWordLists indexes synonym groups by each of their words
+func newWordLists(contractions map[string]string, groups [][]string) wordLists {
+	lists := wordLists{contractions: contractions, synonyms: map[string]string{}}
+	for _, group := range groups {
+		for _, word := range group {
+			lists.synonyms[word] = group[0]
+		}
+	}
+	return lists
+}
+
+// baseLanguage strips the region or script from a code: "pt-BR" becomes "pt"
+func baseLanguage(language string) string {
+	base, _, _ := strings.Cut(strings.ToLower(language), "-")
+	return base
+}
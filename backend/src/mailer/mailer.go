@@ -0,0 +1,66 @@
+// mailer/mailer.go
+package mailer
+
+import (
+	"Delingo/src/config"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the application, set by Init
+var Default Mailer
+
+// Init builds Default from the MAIL_DRIVER setting: "smtp" sends real mail and
+// "outbox" (the default) writes each message to a file for local development
+func Init() error {
+	switch config.MailDriver {
+	case "smtp":
+		if config.SMTPHost == "" {
+			return errors.New("SMTP_HOST is required for the smtp mail driver")
+		}
+		Default = &SMTPMailer{
+			Host:     config.SMTPHost,
+			Port:     config.SMTPPort,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     config.MailFrom,
+		}
+	case "", "outbox":
+		Default = &OutboxMailer{Dir: config.MailOutboxDir, From: config.MailFrom}
+	default:
+		return fmt.Errorf("unknown mail driver %q", config.MailDriver)
+	}
+	return nil
+}
+
+// Send delivers msg with the Default mailer
+func Send(msg Message) error {
+	if Default == nil {
+		return errors.New("mailer is not initialized")
+	}
+	return Default.Send(msg)
+}
+
+// validate rejects messages that could inject extra headers
+func (m Message) validate() error {
+	if m.To == "" {
+		return errors.New("message has no recipient")
+	}
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return errors.New("message headers must not contain line breaks")
+	}
+	return nil
+}
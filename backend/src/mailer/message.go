@@ -0,0 +1,31 @@
+// mailer/message.go
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// format renders msg as an RFC 5322 message with a UTF-8 plain-text body
+func format(from string, msg Message, now time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+
+	// SMTP requires CRLF line endings in the body too
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	buf.WriteString(body)
+	if !strings.HasSuffix(body, "\r\n") {
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
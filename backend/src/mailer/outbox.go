@@ -0,0 +1,38 @@
+// mailer/outbox.go
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OutboxMailer writes every message to an .eml file in Dir instead of sending it.
+// It is meant for local development and tests, where the files can be opened
+// or read back to follow links.
+type OutboxMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes msg to a new file in the outbox directory
+func (m *OutboxMailer) Send(msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	return os.WriteFile(filepath.Join(m.Dir, name), format(m.From, msg, now), 0o644)
+}
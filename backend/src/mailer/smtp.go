@@ -0,0 +1,43 @@
+// mailer/smtp.go
+package mailer
+
+import (
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends mail through an SMTP server, upgrading to TLS with STARTTLS
+// when the server offers it
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers msg through the SMTP server
+func (m *SMTPMailer) Send(msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	addr := net.JoinHostPort(m.Host, m.Port)
+	return smtp.SendMail(addr, auth, from.Address, []string{to.Address}, format(m.From, msg, time.Now()))
+}
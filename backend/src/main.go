@@ -1,23 +1,48 @@
 package main
 
 import (
+	"Delingo/src/config"
+	"Delingo/src/controllers"
+	"Delingo/src/mailer"
 	"Delingo/src/routes"
+	"Delingo/src/storage"
 	"Delingo/src/utils"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	_ "time/tzdata" // learners' time zones must resolve even on hosts without zoneinfo
 
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/mux"
 )
 
 func main() {
+	// Load the token signing settings
+	config.LoadAuthConfig()
+
+	// Initialize the mailer
+	if err := mailer.Init(); err != nil {
+		log.Fatalf("Error initializing mailer: %v", err)
+	}
+
 	// Initialize the database
-	err := utils.InitDB()
-	if err != nil {
+	if err := utils.InitDB(); err != nil {
 		log.Fatalf("Error initializing database: %v", err)
 	}
 
+	// React to domain events such as streak milestones
+	controllers.SubscribeEvents()
+
+	// Initialize blob storage for uploads
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Error initializing storage: %v", err)
+	}
+
+	// Promote the configured admin accounts
+	if err := utils.PromoteAdmins(adminEmails()); err != nil {
+		log.Fatalf("Error promoting admins: %v", err)
+	}
+
 	// Initialize logger
 	logFile, err := os.OpenFile("app.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
@@ -26,31 +51,19 @@ func main() {
 	defer logFile.Close()
 	log.SetOutput(logFile)
 
-	// Initialize the router
-	router := mux.NewRouter()
+	// One Gin engine serves every route
+	router := gin.New()
 
-	// Create a new Gin engine
-	ginEngine := gin.Default()
-
-	// Register Gin routes
-	routes.ForumRoutes(ginEngine)
-
-	// Serve Gin on a specific path (e.g., "/api")
-	router.Handle("/api/", http.StripPrefix("/api", ginEngine))
+	// Only believe X-Forwarded-For when running behind a trusted proxy
+	if !config.TrustProxyHeaders {
+		if err := router.SetTrustedProxies(nil); err != nil {
+			log.Fatalf("Error configuring proxies: %v", err)
+		}
+	}
 
 	// Register routes
 	routes.RegisterRoutes(router)
 
-	// Add basic routes
-	router.HandleFunc("/signup", signupHandler).Methods(http.MethodPost)
-	router.HandleFunc("/login", loginHandler).Methods(http.MethodPost)
-
-	// Add a health check route
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		log.Println("Health check triggered")
-		w.Write([]byte("Hello, World!"))
-	})
-
 	// Start the server
 	port := "8080"
 	log.Printf("Server starting on port %s\n", port)
@@ -59,19 +72,13 @@ func main() {
 	}
 }
 
-// signupHandler handles user signup requests
-func signupHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("Signup handler triggered")
-	// Process signup request (dummy response for now)
-	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte("User registered successfully"))
-	log.Println("Signup completed")
-}
-
-// loginHandler handles user login requests
-func loginHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("Login handler triggered")
-	// Process login request (dummy response for now)
-	w.Write([]byte("User logged in successfully"))
-	log.Println("Login completed")
+// adminEmails parses the comma separated ADMIN_EMAILS setting
+func adminEmails() []string {
+	var emails []string
+	for _, email := range strings.Split(config.AdminEmails, ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
 }
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"Delingo/src/config"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS is a Gin middleware that lets the configured frontend origins call the API
+// and answers their preflight requests
+func CORS() gin.HandlerFunc {
+	allowed := map[string]bool{}
+	allowAll := false
+	for _, origin := range strings.Split(config.CORSOrigins, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "*" {
+			allowAll = true
+		} else if origin != "" {
+			allowed[origin] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin != "" && (allowAll || allowed[origin]) {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			c.Header("Access-Control-Expose-Headers", "Retry-After")
+			c.Header("Access-Control-Max-Age", "600")
+			c.Header("Vary", "Origin")
+		}
+
+		if c.Request.Method == http.MethodOptions && origin != "" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
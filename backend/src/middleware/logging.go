@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger is a Gin middleware that writes one line per request to the app log
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.Request.URL.Path
+		if c.Request.URL.RawQuery != "" {
+			path += "?" + c.Request.URL.RawQuery
+		}
+		log.Printf("%s %s %d %s %s", c.Request.Method, path, c.Writer.Status(), time.Since(start).Round(time.Microsecond), c.ClientIP())
+		for _, err := range c.Errors {
+			log.Printf("  error: %v", err.Err)
+		}
+	}
+}
+
+// Recovery is a Gin middleware that turns a panicking handler into a JSON 500
+// and logs the stack, so one bad request can't take the server down
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered interface{}) {
+		log.Printf("Panic serving %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, recovered, debug.Stack())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	})
+}
+
+// ErrorHandler is a Gin middleware that answers with the shared JSON error shape
+// when a handler recorded an error with c.Error but wrote no response
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest {
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{"error": http.StatusText(status)})
+	}
+}
+
+// NotFound answers unknown routes with the shared JSON error shape
+func NotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+}
+
+// MethodNotAllowed answers known routes called with the wrong method
+func MethodNotAllowed(c *gin.Context) {
+	c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "Method not allowed"})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"Delingo/src/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions a route can require
+const (
+	PermForumWrite    = "forum:write"    // create threads, posts, comments and votes
+	PermForumModerate = "forum:moderate" // edit or delete anyone's forum content
+	PermContentAuthor = "content:author" // write course content
+	PermUsersManage   = "users:manage"   // administer user accounts
+)
+
+// rolePermissions lists what each role may do
+var rolePermissions = map[string][]string{
+	models.RoleAdmin:     {PermForumWrite, PermForumModerate, PermContentAuthor, PermUsersManage},
+	models.RoleModerator: {PermForumWrite, PermForumModerate},
+	models.RoleTeacher:   {PermForumWrite, PermContentAuthor},
+	models.RoleLearner:   {PermForumWrite},
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether role grants permission
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission is a Gin middleware that only lets through callers whose role
+// grants permission. It must run after JWTAuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c.GetString("role"), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to do this"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
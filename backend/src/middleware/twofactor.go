@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"Delingo/src/models"
+	"Delingo/src/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TwoFactorRequired reports whether an admin has made two-factor authentication mandatory for role
+func TwoFactorRequired(role string) (bool, error) {
+	var policy models.RolePolicy
+	err := utils.GormDB.Where("role = ?", role).First(&policy).Error
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	return policy.RequireTwoFactor, err
+}
+
+// RequireTwoFactorPolicy is a Gin middleware that holds back users whose role must
+// use two-factor authentication until they have enrolled. It must run after
+// JWTAuthMiddleware, and is left off the /account/2fa routes used to enroll.
+func RequireTwoFactorPolicy() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required, err := TwoFactorRequired(c.GetString("role"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor policy"})
+			c.Abort()
+			return
+		}
+
+		if required {
+			var user models.User
+			err := utils.GormDB.Select("id", "totp_enabled_at").First(&user, c.GetUint("userID")).Error
+			if err != nil || !user.TwoFactorEnabled() {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":                     "Your role requires two-factor authentication to be set up",
+					"two_factor_setup_required": true,
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// UserBan keeps a user from logging in, until ExpiresAt for a suspension or
+// for good when ExpiresAt is nil. Lifted bans stay as history.
+type UserBan struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    int        `json:"user_id" gorm:"not null;index"`
+	Reason    string     `json:"reason" gorm:"not null"`
+	BannedBy  int        `json:"banned_by" gorm:"not null"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	LiftedAt  *time.Time `json:"lifted_at"`
+	LiftedBy  *int       `json:"lifted_by"`
+}
+
+// Active reports whether the ban still applies at t
+func (b UserBan) Active(t time.Time) bool {
+	return b.LiftedAt == nil && (b.ExpiresAt == nil || t.Before(*b.ExpiresAt))
+}
+
+// Actions recorded in the audit log
+const (
+	AuditUserSearch      = "user.search"
+	AuditUserView        = "user.view"
+	AuditUserBan         = "user.ban"
+	AuditUserUnban       = "user.unban"
+	AuditPasswordReset   = "user.force_password_reset"
+	AuditImpersonate     = "user.impersonate"
+	AuditImpersonated    = "user.impersonated_request" // a request an admin made while acting as the user
+	AuditRoleChange      = "user.role"
+	AuditUserErase       = "user.erase"
+	AuditTwoFactorPolicy = "policy.two_factor"
+	AuditLockoutClear    = "lockout.clear"
+	AuditXPGrant         = "xp.grant"
+	AuditBadgeMint       = "badge.mint"
+)
+
+// AuditLog records an administrative action: who did what, to whom and why
+type AuditLog struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ActorID      int       `json:"actor_id" gorm:"not null;index"`
+	Action       string    `json:"action" gorm:"not null;index"`
+	TargetUserID *int      `json:"target_user_id" gorm:"index"`
+	Details      JSONMap   `json:"details" gorm:"type:jsonb;not null;default:'{}'"`
+	IPAddress    string    `json:"ip_address"`
+	CreatedAt    time.Time `json:"created_at"`
+}
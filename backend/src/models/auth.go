@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// AuthNonce is a single-use challenge handed to a wallet before it signs in
+type AuthNonce struct {
+	ID        uint       `json:"-" gorm:"primaryKey"`
+	Nonce     string     `json:"nonce" gorm:"uniqueIndex;not null"`
+	Chain     string     `json:"chain" gorm:"not null"` // e.g., 'ethereum'
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"-"`
+	IPAddress string     `json:"-" gorm:"index"` // who asked for it, for the per-IP cap
+	CreatedAt time.Time  `json:"-"`
+}
+
+// Purposes a UserToken can be issued for
+const (
+	TokenEmailVerification = "email_verification"
+	TokenPasswordReset     = "password_reset"
+)
+
+// UserToken is a single-use secret emailed to a user. Only its hash is stored.
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    int       `gorm:"not null;index"`
+	Purpose   string    `gorm:"not null"`
+	Subject   string    // the email address the token was sent to
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// RecoveryCode is a one-time code that stands in for a TOTP code when the
+// authenticator is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    int    `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// RolePolicy holds security requirements an admin has set for a role
+type RolePolicy struct {
+	Role             string    `json:"role" gorm:"primaryKey"`
+	RequireTwoFactor bool      `json:"require_two_factor" gorm:"not null;default:false"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Scopes failed logins are counted under
+const (
+	ThrottleAccount = "account" // keyed by the email (or user, for 2FA codes) being tried
+	ThrottleIP      = "ip"      // keyed by the client IP address
+)
+
+// LoginThrottle counts recent failed logins for one account or IP address. It
+// lives in Postgres so every replica sees the same counters.
+type LoginThrottle struct {
+	ID            uint   `gorm:"primaryKey"`
+	Scope         string `gorm:"not null;uniqueIndex:idx_throttle_scope_key"`
+	Key           string `gorm:"not null;uniqueIndex:idx_throttle_scope_key"`
+	Failures      int    `gorm:"not null;default:0"`
+	LockedUntil   *time.Time
+	LastFailureAt time.Time `gorm:"not null"`
+}
+
+// LockoutEvent is the audit record written each time an account or IP is locked out
+type LockoutEvent struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Scope       string    `json:"scope" gorm:"not null;index"`
+	Key         string    `json:"key" gorm:"not null;index"`
+	IPAddress   string    `json:"ip_address"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+	CreatedAt   time.Time `json:"created_at"`
+}
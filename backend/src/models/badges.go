@@ -1,10 +1,49 @@
 package models
 
+import "time"
+
+// Badge is an achievement learners earn when its criteria hold. Criteria is
+// the JSON of an achievements.Criteria: a rule such as "streak >= 30" and
+// whether the badge is minted as an NFT.
 type Badge struct {
-	ID          uint   `gorm:"primary_key"`
-	Name        string `gorm:"not null"`
-	Description string
-	Criteria    string `gorm:"type:jsonb"` // Using jsonb for storing criteria, if needed
-	TokenID     uint64 `gorm:"not null"`   // Storing the NFT's tokenID
-	TokenURI    string // URI for badge metadata
+	ID          uint   `json:"id" gorm:"primary_key"`
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	Criteria    string `json:"criteria" gorm:"type:jsonb"`
+	TokenID     uint64 `json:"token_id" gorm:"not null"` // Storing the NFT's tokenID
+	TokenURI    string `json:"token_uri"`                // URI for badge metadata
+}
+
+// UserBadge records a badge earned by a user
+type UserBadge struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	UserID   int       `json:"user_id" gorm:"not null;uniqueIndex:idx_user_badge"`
+	BadgeID  uint      `json:"badge_id" gorm:"not null;uniqueIndex:idx_user_badge"`
+	Badge    Badge     `json:"badge" gorm:"constraint:OnDelete:CASCADE"`
+	EarnedAt time.Time `json:"earned_at"`
+}
+
+// Badge mint statuses
+const (
+	MintPending = "pending"
+	MintSent    = "sent"
+	MintFailed  = "failed"
+)
+
+// BadgeMint is an outbox entry for minting an earned badge on the BadgeNFT
+// contract to the learner's wallet. A relayer holding the contract owner's key
+// sends CallData to Contract and reports the transaction back.
+type BadgeMint struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      int       `json:"user_id" gorm:"not null;index"`
+	UserBadgeID uint      `json:"user_badge_id" gorm:"not null;uniqueIndex"`
+	BadgeID     uint      `json:"badge_id" gorm:"not null"`
+	Contract    string    `json:"contract" gorm:"not null"`
+	Address     string    `json:"address" gorm:"not null"`   // the learner's wallet
+	CallData    string    `json:"call_data" gorm:"not null"` // the mintBadge call, 0x-prefixed hex
+	Status      string    `json:"status" gorm:"not null;default:pending;index"`
+	TxHash      string    `json:"tx_hash"`
+	Error       string    `json:"error"` // why the last attempt failed
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
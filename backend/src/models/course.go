@@ -0,0 +1,108 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+// Course content is a tree: a course teaches one language to speakers of
+// another and is split into units, units into skills, skills into lessons and
+// lessons into exercises. Siblings are ordered by Position. Deleting a node
+// deletes everything under it.
+
+// Course is everything taught for one source/target language pair
+type Course struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SourceLanguage string    `json:"source_language" gorm:"not null;index:idx_course_languages"` // what learners already speak, e.g. "en"
+	TargetLanguage string    `json:"target_language" gorm:"not null;index:idx_course_languages"` // what they learn, e.g. "es"
+	Title          string    `json:"title" gorm:"not null"`
+	Description    string    `json:"description" gorm:"default:''"`
+	Published      bool      `json:"published" gorm:"not null;default:false"` // learners only see published courses
+	CreatedBy      int       `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Units          []Unit    `json:"units,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// Unit is a themed section of a course
+type Unit struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CourseID    uint      `json:"course_id" gorm:"not null;index"`
+	Position    int       `json:"position" gorm:"not null"`
+	Title       string    `json:"title" gorm:"not null"`
+	Description string    `json:"description" gorm:"default:''"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Skills      []Skill   `json:"skills,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// Skill is a topic within a unit, e.g. "Food" or "Past tense". A skill can
+// require other skills of the same course to be finished first.
+type Skill struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	UnitID          uint      `json:"unit_id" gorm:"not null;index"`
+	Position        int       `json:"position" gorm:"not null"`
+	Title           string    `json:"title" gorm:"not null"`
+	Description     string    `json:"description" gorm:"default:''"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Lessons         []Lesson  `json:"lessons,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	PrerequisiteIDs []uint    `json:"prerequisite_ids" gorm:"-"` // loaded from SkillPrerequisite
+}
+
+// SkillPrerequisite says SkillID stays locked until PrerequisiteID is finished
+type SkillPrerequisite struct {
+	SkillID        uint  `json:"skill_id" gorm:"primaryKey"`
+	PrerequisiteID uint  `json:"prerequisite_id" gorm:"primaryKey;index"`
+	Skill          Skill `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Prerequisite   Skill `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// Lesson is one sitting's worth of exercises
+type Lesson struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	SkillID   uint       `json:"skill_id" gorm:"not null;index"`
+	Position  int        `json:"position" gorm:"not null"`
+	Title     string     `json:"title" gorm:"not null"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Exercises []Exercise `json:"exercises,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// Exercise is a single task in a lesson. Content holds what the type needs,
+// solution included, e.g. the choices of a multiple choice question and which
+// is right; package exercises checks it and hides the solution from learners.
+type Exercise struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	LessonID  uint      `json:"lesson_id" gorm:"not null;index"`
+	Position  int       `json:"position" gorm:"not null"`
+	Type      string    `json:"type" gorm:"not null"`
+	Prompt    string    `json:"prompt" gorm:"not null"`
+	Content   JSONMap   `json:"content" gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// languageCode matches BCP 47 style tags such as "en", "pt-BR" or "zh-Hant"
+var languageCode = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidLanguageCode reports whether code is a language tag courses and
+// preferences accept
+func ValidLanguageCode(code string) bool {
+	return languageCode.MatchString(code)
+}
+
+// Validate checks the fields an author sets on a course
+func (c Course) Validate() error {
+	if !ValidLanguageCode(c.SourceLanguage) || !ValidLanguageCode(c.TargetLanguage) {
+		return errors.New("source_language and target_language must be language codes such as \"en\" or \"pt-BR\"")
+	}
+	if c.SourceLanguage == c.TargetLanguage {
+		return errors.New("source_language and target_language must differ")
+	}
+	if c.Title == "" {
+		return errors.New("title is required")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Identity providers a user can log in with
+const (
+	ProviderEmail    = "email"
+	ProviderEthereum = "ethereum"
+	ProviderSolana   = "solana"
+)
+
+// UserIdentity links one login method (an email or a wallet) to an account
+type UserIdentity struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     int        `json:"user_id" gorm:"not null;index"`
+	Provider   string     `json:"provider" gorm:"not null;uniqueIndex:idx_identity_provider_subject"` // e.g., 'email', 'ethereum', 'solana'
+	Subject    string     `json:"subject" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`  // email address or wallet address
+	VerifiedAt *time.Time `json:"verified_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// JSONMap is a free-form JSON object stored as JSONB
+type JSONMap map[string]interface{}
+
+// Scan implements sql.Scanner. Values that aren't a JSON object read as empty.
+func (d *JSONMap) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	*d = JSONMap{}
+	if len(data) > 0 && json.Unmarshal(data, d) != nil {
+		*d = JSONMap{}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (d JSONMap) Value() (driver.Value, error) {
+	if d == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(d)
+	return string(data), err
+}
+
+// IDList is a list of row IDs stored as a JSONB array
+type IDList []uint
+
+// Scan implements sql.Scanner
+func (l *IDList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	*l = IDList{}
+	if len(data) > 0 && json.Unmarshal(data, l) != nil {
+		*l = IDList{}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (l IDList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]uint(l))
+	return string(data), err
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// LessonSession is a learner working through a lesson. The exercises are fixed
+// when it starts, so edits to the lesson don't change a session in progress.
+// A practice session reviews exercises from any lessons and has no LessonID.
+type LessonSession struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      int        `json:"user_id" gorm:"not null;index"`
+	LessonID    uint       `json:"lesson_id" gorm:"not null;index"`
+	Practice    bool       `json:"practice" gorm:"not null;default:false"`
+	ExerciseIDs IDList     `json:"exercise_ids" gorm:"type:jsonb;not null;default:'[]'"`
+	Answered    int        `json:"answered" gorm:"not null;default:0"`
+	Correct     int        `json:"correct" gorm:"not null;default:0"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"` // set once every exercise is answered
+}
+
+// ExerciseAnswer is a learner's graded answer to one exercise of a session
+type ExerciseAnswer struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SessionID  uint      `json:"session_id" gorm:"not null;uniqueIndex:idx_session_exercise"`
+	ExerciseID uint      `json:"exercise_id" gorm:"not null;uniqueIndex:idx_session_exercise"`
+	Answer     JSONMap   `json:"answer" gorm:"type:jsonb;not null;default:'{}'"`
+	Correct    bool      `json:"correct"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Kinds of review item
+const (
+	ReviewWord     = "word"
+	ReviewSentence = "sentence"
+)
+
+// ReviewItem is a word or sentence a learner has met in exercises, with its
+// spaced-repetition schedule. Key is the text normalized for its language, so
+// the same word met in different exercises is one item.
+type ReviewItem struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         int        `json:"user_id" gorm:"not null;uniqueIndex:idx_review_item;index:idx_review_due,priority:1"`
+	Language       string     `json:"language" gorm:"not null;uniqueIndex:idx_review_item"`
+	Key            string     `json:"-" gorm:"not null;uniqueIndex:idx_review_item"`
+	Kind           string     `json:"kind" gorm:"not null"`
+	Text           string     `json:"text" gorm:"not null"`
+	ExerciseID     uint       `json:"exercise_id" gorm:"index"` // the exercise it was last met in, to practise it with
+	Ease           float64    `json:"ease" gorm:"not null;default:2.5"`
+	IntervalDays   int        `json:"interval_days" gorm:"not null;default:0"`
+	Repetitions    int        `json:"repetitions" gorm:"not null;default:0"`
+	Lapses         int        `json:"lapses" gorm:"not null;default:0"`
+	DueAt          time.Time  `json:"due_at" gorm:"not null;index:idx_review_due,priority:2"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
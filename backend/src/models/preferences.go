@@ -0,0 +1,301 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PreferencesVersion is the current version of the Preferences schema. Bump it
+// and add a step to preferencesMigrations whenever the stored shape changes.
+const PreferencesVersion = 1
+
+// Limits on preference values
+const (
+	MinDailyXPGoal     = 5
+	MaxDailyXPGoal     = 500
+	MaxTargetLanguages = 5
+)
+
+// Preferences are a learner's settings, stored as JSONB on their profile
+type Preferences struct {
+	Version            int      `json:"version"`
+	NativeLanguage     string   `json:"native_language"`  // language code, e.g. "en" or "pt-BR"
+	TargetLanguages    []string `json:"target_languages"` // languages being learned
+	DailyXPGoal        int      `json:"daily_xp_goal"`
+	ReminderTime       string   `json:"reminder_time"` // "HH:MM" in Timezone, empty for no reminder
+	Timezone           string   `json:"timezone"`      // IANA name, e.g. "Europe/Berlin"
+	SoundEffects       bool     `json:"sound_effects"`
+	EmailNotifications bool     `json:"email_notifications"`
+	PushNotifications  bool     `json:"push_notifications"`
+}
+
+// DefaultPreferences returns the settings a new learner starts with
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Version:            PreferencesVersion,
+		NativeLanguage:     "en",
+		TargetLanguages:    []string{},
+		DailyXPGoal:        20,
+		Timezone:           "UTC",
+		SoundEffects:       true,
+		EmailNotifications: true,
+		PushNotifications:  true,
+	}
+}
+
+// Validate checks every field against the current schema
+func (p Preferences) Validate() error {
+	if p.Version != PreferencesVersion {
+		return fmt.Errorf("preferences version must be %d", PreferencesVersion)
+	}
+	if !ValidLanguageCode(p.NativeLanguage) {
+		return errors.New("native_language must be a language code like \"en\", \"pt-BR\" or \"zh-Hant\"")
+	}
+	if len(p.TargetLanguages) > MaxTargetLanguages {
+		return fmt.Errorf("at most %d target_languages are allowed", MaxTargetLanguages)
+	}
+	seen := map[string]bool{}
+	for _, lang := range p.TargetLanguages {
+		if !ValidLanguageCode(lang) {
+			return fmt.Errorf("target language %q is not a language code", lang)
+		}
+		if lang == p.NativeLanguage {
+			return errors.New("target_languages must not include the native language")
+		}
+		if seen[lang] {
+			return fmt.Errorf("target language %q is listed twice", lang)
+		}
+		seen[lang] = true
+	}
+	if p.DailyXPGoal < MinDailyXPGoal || p.DailyXPGoal > MaxDailyXPGoal {
+		return fmt.Errorf("daily_xp_goal must be between %d and %d", MinDailyXPGoal, MaxDailyXPGoal)
+	}
+	if p.ReminderTime != "" {
+		if _, err := time.Parse("15:04", p.ReminderTime); err != nil {
+			return errors.New("reminder_time must be HH:MM")
+		}
+	}
+	if _, err := p.Location(); err != nil {
+		return errors.New("timezone must be an IANA time zone such as \"Europe/Berlin\"")
+	}
+	return nil
+}
+
+// Location returns the learner's time zone
+func (p Preferences) Location() (*time.Location, error) {
+	if p.Timezone == "" || strings.EqualFold(p.Timezone, "local") {
+		return nil, errors.New("unknown time zone")
+	}
+	return time.LoadLocation(p.Timezone)
+}
+
+// preferencesMigrations upgrade stored preferences one version at a time;
+// the step at index n turns version n into version n+1
+var preferencesMigrations = []func(map[string]interface{}){
+	// 0 -> 1: settings saved before the schema existed. Keep whatever already
+	// matches a current field and fill the rest with defaults.
+	func(raw map[string]interface{}) {
+		defaults, _ := json.Marshal(DefaultPreferences())
+		var fill map[string]interface{}
+		json.Unmarshal(defaults, &fill)
+		for key, value := range fill {
+			if _, ok := raw[key]; !ok {
+				raw[key] = value
+			}
+		}
+	},
+}
+
+// MigratePreferences upgrades stored preferences JSON to the current version.
+// Anything that isn't a JSON object is treated as empty version 0 settings.
+func MigratePreferences(data []byte) (Preferences, error) {
+	raw := map[string]interface{}{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &raw); err != nil || raw == nil {
+			raw = map[string]interface{}{}
+		}
+	}
+
+	version := 0
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > PreferencesVersion {
+		return Preferences{}, fmt.Errorf("preferences version %d is newer than this server understands", version)
+	}
+	for ; version < PreferencesVersion; version++ {
+		preferencesMigrations[version](raw)
+		raw["version"] = version + 1
+	}
+
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return Preferences{}, err
+	}
+	prefs := DefaultPreferences()
+	if err := json.Unmarshal(upgraded, &prefs); err != nil {
+		// A field of the wrong type can't be carried forward
+		prefs = DefaultPreferences()
+	}
+	if prefs.TargetLanguages == nil {
+		prefs.TargetLanguages = []string{}
+	}
+	return prefs, nil
+}
+
+// Scan implements sql.Scanner, upgrading older stored versions as they are read
+func (p *Preferences) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Preferences", value)
+	}
+
+	prefs, err := MigratePreferences(data)
+	if err != nil {
+		return err
+	}
+	*p = prefs
+	return nil
+}
+
+// Value implements driver.Valuer
+func (p Preferences) Value() (driver.Value, error) {
+	if p.Version == 0 {
+		p = DefaultPreferences()
+	}
+	if p.TargetLanguages == nil {
+		p.TargetLanguages = []string{}
+	}
+	data, err := json.Marshal(p)
+	return string(data), err
+}
+
+// socialHosts lists the sites a social link may point to
+var socialHosts = map[string][]string{
+	"twitter":   {"twitter.com", "x.com"},
+	"github":    {"github.com"},
+	"linkedin":  {"linkedin.com"},
+	"youtube":   {"youtube.com", "youtu.be"},
+	"instagram": {"instagram.com"},
+	"telegram":  {"t.me"},
+}
+
+// SocialLinks are a user's profile links, one per supported site, stored as JSONB
+type SocialLinks struct {
+	Twitter   string `json:"twitter,omitempty"`
+	GitHub    string `json:"github,omitempty"`
+	LinkedIn  string `json:"linkedin,omitempty"`
+	YouTube   string `json:"youtube,omitempty"`
+	Instagram string `json:"instagram,omitempty"`
+	Telegram  string `json:"telegram,omitempty"`
+}
+
+// Validate checks that every link is an https URL on its site
+func (s SocialLinks) Validate() error {
+	links := map[string]string{
+		"twitter":   s.Twitter,
+		"github":    s.GitHub,
+		"linkedin":  s.LinkedIn,
+		"youtube":   s.YouTube,
+		"instagram": s.Instagram,
+		"telegram":  s.Telegram,
+	}
+	for site, link := range links {
+		if link == "" {
+			continue
+		}
+		u, err := url.Parse(link)
+		if err != nil || u.Scheme != "https" || len(link) > 300 || !hostAllowed(u.Hostname(), socialHosts[site]) {
+			return fmt.Errorf("%s must be an https link to %s", site, socialHosts[site][0])
+		}
+	}
+	return nil
+}
+
+// hostAllowed reports whether host is one of hosts or a subdomain of one
+func hostAllowed(host string, hosts []string) bool {
+	host = strings.ToLower(host)
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan implements sql.Scanner. Values that aren't a JSON object read as no links.
+func (s *SocialLinks) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	*s = SocialLinks{}
+	if len(data) > 0 && json.Unmarshal(data, s) != nil {
+		*s = SocialLinks{}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (s SocialLinks) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	return string(data), err
+}
+
+// Avatars holds an uploaded avatar's thumbnails, stored as JSONB. Clients see
+// only the URLs, keyed by size name.
+type Avatars struct {
+	URLs map[string]string // public URL by size name
+	Keys []string          // storage keys, so the files can be removed later
+}
+
+// MarshalJSON renders the thumbnail URLs
+func (a Avatars) MarshalJSON() ([]byte, error) {
+	if a.URLs == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(a.URLs)
+}
+
+// avatarsJSON is how Avatars are stored; unlike the API it keeps the keys
+type avatarsJSON struct {
+	URLs map[string]string `json:"urls,omitempty"`
+	Keys []string          `json:"keys,omitempty"`
+}
+
+// Scan implements sql.Scanner. Values that aren't a JSON object read as no avatar.
+func (a *Avatars) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	var stored avatarsJSON
+	if len(data) > 0 && json.Unmarshal(data, &stored) != nil {
+		stored = avatarsJSON{}
+	}
+	*a = Avatars{URLs: stored.URLs, Keys: stored.Keys}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (a Avatars) Value() (driver.Value, error) {
+	data, err := json.Marshal(avatarsJSON{URLs: a.URLs, Keys: a.Keys})
+	return string(data), err
+}
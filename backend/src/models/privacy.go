@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Who can see a part of a public profile
+const (
+	VisibilityPublic  = "public"
+	VisibilityFriends = "friends"
+	VisibilityPrivate = "private"
+)
+
+// ProfilePrivacy sets who may see each part of a user's public profile, stored
+// as JSONB. The owner always sees everything; username and avatar are always public.
+type ProfilePrivacy struct {
+	About      string `json:"about"` // bio, location, website and social links
+	Level      string `json:"level"`
+	Streak     string `json:"streak"`
+	Badges     string `json:"badges"`
+	Reputation string `json:"reputation"` // forum reputation
+	Wallets    string `json:"wallets"`    // verified wallet addresses
+	Activity   string `json:"activity"`   // what shows up in friends' feeds
+}
+
+// DefaultProfilePrivacy returns the settings a new user starts with. Wallets
+// are private until the owner chooses to show them, and activity is shared
+// with friends.
+func DefaultProfilePrivacy() ProfilePrivacy {
+	return ProfilePrivacy{
+		About:      VisibilityPublic,
+		Level:      VisibilityPublic,
+		Streak:     VisibilityPublic,
+		Badges:     VisibilityPublic,
+		Reputation: VisibilityPublic,
+		Wallets:    VisibilityPrivate,
+		Activity:   VisibilityFriends,
+	}
+}
+
+// Validate checks that every setting is a known visibility
+func (p ProfilePrivacy) Validate() error {
+	settings := map[string]string{
+		"about":      p.About,
+		"level":      p.Level,
+		"streak":     p.Streak,
+		"badges":     p.Badges,
+		"reputation": p.Reputation,
+		"wallets":    p.Wallets,
+		"activity":   p.Activity,
+	}
+	for field, visibility := range settings {
+		switch visibility {
+		case VisibilityPublic, VisibilityFriends, VisibilityPrivate:
+		default:
+			return fmt.Errorf("privacy.%s must be public, friends or private", field)
+		}
+	}
+	return nil
+}
+
+// Scan implements sql.Scanner. Missing or unknown settings fall back to the defaults.
+func (p *ProfilePrivacy) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("cannot scan privacy settings")
+	}
+
+	*p = DefaultProfilePrivacy()
+	if len(data) > 0 && (json.Unmarshal(data, p) != nil || p.Validate() != nil) {
+		*p = DefaultProfilePrivacy()
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (p ProfilePrivacy) Value() (driver.Value, error) {
+	if p == (ProfilePrivacy{}) {
+		p = DefaultProfilePrivacy()
+	}
+	data, err := json.Marshal(p)
+	return string(data), err
+}
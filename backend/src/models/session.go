@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session is one logged-in device. Access tokens name their session, and the
+// refresh token that renews them is stored only as a hash and rotated on use.
+type Session struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            int        `json:"-" gorm:"not null;index"`
+	RefreshTokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	PreviousTokenHash string     `json:"-" gorm:"index"` // the refresh token rotated out last, kept to spot reuse
+	UserAgent         string     `json:"device"`
+	IPAddress         string     `json:"ip_address"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastSeenAt        time.Time  `json:"last_seen_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RevokedAt         *time.Time `json:"-"`
+	ImpersonatorID    *int       `json:"impersonated_by,omitempty"` // the admin acting as the user, for support
+	Current           bool       `json:"current" gorm:"-"`          // set when listing, for the session making the request
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Follow is one user following another. Two users who follow each other are friends.
+type Follow struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	FollowerID int       `json:"follower_id" gorm:"not null;uniqueIndex:idx_follow_pair"`
+	FolloweeID int       `json:"followee_id" gorm:"not null;uniqueIndex:idx_follow_pair;index"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Kinds of activity shown in the feed
+const (
+	ActivityLessonCompleted = "lesson_completed"
+	ActivityBadgeEarned     = "badge_earned"
+	ActivityLeagueResult    = "league_result"
+	ActivityThreadCreated   = "thread_created"
+	ActivityStreakMilestone = "streak_milestone"
+)
+
+// Activity is something a user did that their friends see in their feed.
+// Data holds the details for the kind, e.g. the thread's ID and title.
+type Activity struct {
+	ID        uint      `json:"id" gorm:"primaryKey;index:idx_activity_user,priority:2"`
+	UserID    int       `json:"user_id" gorm:"not null;index:idx_activity_user,priority:1"`
+	Kind      string    `json:"kind" gorm:"not null"`
+	Data      JSONMap   `json:"data" gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Streak is a learner's run of days with practice, counted in their own time
+// zone. Profile.Streak shows Current.
+type Streak struct {
+	UserID    int       `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Current   int       `json:"current" gorm:"not null;default:0"`
+	Longest   int       `json:"longest" gorm:"not null;default:0"`
+	Freezes   int       `json:"freezes" gorm:"not null;default:0"`  // each covers one missed day
+	LastDay   string    `json:"last_day" gorm:"size:10;default:''"` // the last day practised or frozen, as YYYY-MM-DD
+	Timezone  string    `json:"timezone" gorm:"default:''"`         // the time zone LastDay was counted in
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StreakDay is a day in a learner's streak history: practised, or missed but
+// covered by a freeze
+type StreakDay struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	UserID    int       `json:"-" gorm:"not null;uniqueIndex:idx_streak_day"`
+	Day       string    `json:"day" gorm:"size:10;not null;uniqueIndex:idx_streak_day"` // YYYY-MM-DD in the learner's time zone
+	Frozen    bool      `json:"frozen"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,27 +1,86 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
+	"regexp"
+	"strings"
 	"time"
 )
 
+// Roles a user can hold, from most to least privileged
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleTeacher   = "teacher"
+	RoleLearner   = "learner"
+)
+
 type User struct {
 	ID                 int       `json:"id"`
 	Username           string    `json:"username"`
 	Email              string    `json:"email"`
-	Password           string    `json:"password"`
-	EthereumWalletAddr string    `json:"ethereum_wallet_address"`
-	SolanaWalletAddr   string    `json:"solana_wallet_address"`
+	Password           string    `json:"password,omitempty"`
 	RegistrationMethod string    `json:"registration_method"`
+	Role               string    `json:"role" gorm:"not null;default:learner"`
 	CreatedAt          time.Time `json:"created_at"`
+
+	// Two-factor authentication. The secret is set at setup and only used for
+	// login once enrollment is confirmed (TOTPEnabledAt set).
+	TOTPSecret    string     `json:"-"`
+	TOTPEnabledAt *time.Time `json:"-"`
+	TOTPLastStep  int64      `json:"-" gorm:"not null;default:0"` // last accepted time step, to stop code replay
+
+	// Set by an admin to make the user choose a new password before logging in with one
+	PasswordResetRequired bool `json:"-" gorm:"not null;default:false"`
+}
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+	defaultUsername = regexp.MustCompile(`^(?i)user[0-9]+$`)
+)
+
+// DefaultUsername is the username given to an account registered without one
+func DefaultUsername(userID int) string {
+	return fmt.Sprintf("user%d", userID)
+}
+
+// ValidateUsername checks a username userID (0 for a new account) wants to use.
+// Usernames are public profile addresses: 3 to 30 letters, digits or
+// underscores, unique regardless of case. Names shaped like a default username
+// are reserved for their account.
+func ValidateUsername(username string, userID int) error {
+	if !usernamePattern.MatchString(username) {
+		return errors.New("username must be 3 to 30 letters, digits or underscores")
+	}
+	if defaultUsername.MatchString(username) && (userID == 0 || !strings.EqualFold(username, DefaultUsername(userID))) {
+		return errors.New("usernames like \"user123\" are reserved")
+	}
+	return nil
 }
 
+// TwoFactorEnabled reports whether logins must be confirmed with a TOTP code
+func (u User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// Lesson progress statuses
+const (
+	ProgressInProgress = "in-progress"
+	ProgressCompleted  = "completed"
+)
+
+// Progress is how far a learner got in a lesson, recorded as they start and
+// finish sessions of it. Skill, unit and course progress are worked out from it.
 type Progress struct {
-	ID       int     `json:"id"`
-	UserID   int     `json:"user_id"`
-	QuizID   int     `json:"quiz_id"`
-	Status   string  `json:"status"`   // e.g., 'completed', 'in-progress'
-	Progress float32 `json:"progress"` // percentage
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id" gorm:"not null;uniqueIndex:idx_progress_lesson"`
+	LessonID    uint       `json:"lesson_id" gorm:"not null;uniqueIndex:idx_progress_lesson;index"`
+	Status      string     `json:"status" gorm:"not null"` // ProgressInProgress or ProgressCompleted
+	Progress    float32    `json:"progress"`               // best score, as the percentage of exercises answered right
+	CompletedAt *time.Time `json:"completed_at"`           // when the lesson was first finished
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 // Token represents a token deposit object.
@@ -29,16 +88,37 @@ type Token struct {
 	Address string   `json:"address"`
 	Balance *big.Int `json:"balance"`
 }
+
+// Profile holds a user's public details. One is created with every account.
+// Level and streak are awarded by the server and can't be set by the client.
 type Profile struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"user_id"`
-	Bio         string    `json:"bio"`
-	Level       int       `json:"level"`
-	AvatarURL   string    `json:"avatar_url"`
-	Location    string    `json:"location"`
-	Website     string    `json:"website"`
-	Preferences string    `json:"preferences"`
-	SocialLinks string    `json:"social_links"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int            `json:"id"`
+	UserID      int            `json:"user_id" gorm:"uniqueIndex;not null"`
+	Bio         string         `json:"bio" gorm:"default:''"`
+	Level       int            `json:"level" gorm:"not null;default:0"`  // recomputed from the XP ledger
+	Streak      int            `json:"streak" gorm:"not null;default:0"` // current streak, kept in step with the Streak
+	AvatarURL   string         `json:"avatar_url" gorm:"default:''"`     // the large thumbnail
+	Avatars     Avatars        `json:"avatars" gorm:"type:jsonb;not null;default:'{}'"`
+	Location    string         `json:"location" gorm:"default:''"`
+	Website     string         `json:"website" gorm:"default:''"`
+	Preferences Preferences    `json:"preferences" gorm:"type:jsonb;not null;default:'{}'"`
+	SocialLinks SocialLinks    `json:"social_links" gorm:"type:jsonb;not null;default:'{}'"`
+	Privacy     ProfilePrivacy `json:"privacy" gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// ErasedUserID is the author of forum content whose account was erased
+const ErasedUserID = 0
+
+// UserTombstone records that an account was erased, for audit. It keeps no
+// personal data: not the username, email or any wallet address.
+type UserTombstone struct {
+	ID                 uint      `json:"id" gorm:"primaryKey"`
+	UserID             int       `json:"user_id" gorm:"uniqueIndex;not null"` // the erased account's ID
+	RegistrationMethod string    `json:"registration_method"`
+	RegisteredAt       time.Time `json:"registered_at"`
+	ErasedAt           time.Time `json:"erased_at"`
+	ErasedBy           int       `json:"erased_by"` // the user themselves, or the admin who erased them
+	Reason             string    `json:"reason"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Sources of XP
+const (
+	XPLesson      = "lesson"       // finishing a lesson
+	XPReview      = "review"       // finishing a practice session
+	XPQuiz        = "quiz"         // a quiz solved on chain, credited by an admin
+	XPForumUpvote = "forum_upvote" // an upvote on a forum thread or post
+	XPEventBonus  = "event_bonus"  // streak milestones and promotional events
+)
+
+// XPEntry is one award of XP. The ledger is append-only: entries are never
+// changed or removed, and totals, levels and leagues are all summed from it.
+// Ref names what the XP was for, so the same thing is never awarded twice.
+type XPEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"not null;uniqueIndex:idx_xp_ref;index:idx_xp_day,priority:1"`
+	Source    string    `json:"source" gorm:"not null;uniqueIndex:idx_xp_ref"`
+	Ref       string    `json:"ref" gorm:"not null;uniqueIndex:idx_xp_ref"`
+	Amount    int       `json:"amount" gorm:"not null"`
+	Day       string    `json:"day" gorm:"size:10;not null;index:idx_xp_day,priority:2"` // YYYY-MM-DD in the learner's time zone when awarded
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"Delingo/src/controllers"
+	"Delingo/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AccountRoutes(r *gin.RouterGroup) {
+	// Account routes act on the logged-in user
+	accountGroup := r.Group("/account")
+	accountGroup.Use(middleware.JWTAuthMiddleware(), middleware.NoImpersonation(), middleware.RequireTwoFactorPolicy())
+	{
+		// Linked identities (email and wallets)
+		accountGroup.GET("/identities", controllers.GetIdentities)                  // List login methods
+		accountGroup.POST("/identities/email", controllers.LinkEmailIdentity)       // Link an email and password
+		accountGroup.POST("/identities/ethereum", controllers.LinkEthereumIdentity) // Link an Ethereum wallet with a signed SIWE message
+		accountGroup.POST("/identities/solana", controllers.LinkSolanaIdentity)     // Link a Solana wallet with a signed message
+		accountGroup.DELETE("/identities/:id", controllers.UnlinkIdentity)          // Unlink a login method
+
+		// Email verification
+		accountGroup.POST("/email/verification", controllers.ResendVerificationEmail) // Resend the verification link
+	}
+
+	// Two-factor enrollment stays reachable for users the 2FA policy is holding back
+	twoFactorGroup := r.Group("/account/2fa")
+	twoFactorGroup.Use(middleware.JWTAuthMiddleware(), middleware.NoImpersonation())
+	{
+		twoFactorGroup.GET("", controllers.GetTwoFactorStatus)                      // Show 2FA status
+		twoFactorGroup.POST("/setup", controllers.SetupTwoFactor)                   // Start enrollment and get the provisioning URI
+		twoFactorGroup.POST("/enable", controllers.EnableTwoFactor)                 // Confirm enrollment with a code
+		twoFactorGroup.POST("/disable", controllers.DisableTwoFactor)               // Turn 2FA off
+		twoFactorGroup.POST("/recovery-codes", controllers.RegenerateRecoveryCodes) // Replace the recovery codes
+	}
+
+	// Session management for the logged-in user
+	sessionGroup := r.Group("/sessions")
+	sessionGroup.Use(middleware.JWTAuthMiddleware(), middleware.NoImpersonation(), middleware.RequireTwoFactorPolicy())
+	{
+		sessionGroup.GET("", controllers.GetSessions)            // List active sessions
+		sessionGroup.DELETE("", controllers.RevokeOtherSessions) // Revoke every other session
+		sessionGroup.DELETE("/:id", controllers.RevokeSession)   // Revoke one session
+	}
+}
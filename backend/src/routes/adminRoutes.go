@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"Delingo/src/controllers"
+	"Delingo/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(r *gin.RouterGroup) {
+	// Admin routes need users:manage
+	adminGroup := r.Group("/admin")
+	adminGroup.Use(middleware.JWTAuthMiddleware(), middleware.RequireTwoFactorPolicy(), middleware.RequirePermission(middleware.PermUsersManage))
+	{
+		adminGroup.PUT("/users/:id/role", controllers.UpdateUserRole) // Change a user's role
+
+		// User management; every action lands in the audit log
+		adminGroup.GET("/users", controllers.SearchUsers)                            // Search users by email, username or wallet
+		adminGroup.GET("/users/:id", controllers.GetUserDetails)                     // A user's identities, sessions and bans
+		adminGroup.POST("/users/:id/ban", controllers.BanUser)                       // Ban or suspend a user
+		adminGroup.DELETE("/users/:id/ban", controllers.UnbanUser)                   // Lift a ban
+		adminGroup.POST("/users/:id/password-reset", controllers.ForcePasswordReset) // Make a user choose a new password
+		adminGroup.POST("/users/:id/impersonate", controllers.ImpersonateUser)       // Act as a user for support
+		adminGroup.POST("/users/:id/xp", controllers.GrantXP)                        // Credit XP for a quiz or event
+		adminGroup.GET("/audit-log", controllers.GetAuditLog)                        // Admin actions, newest first
+
+		// Two-factor enforcement per role
+		adminGroup.GET("/2fa-policy", controllers.GetTwoFactorPolicies)     // List roles that must use 2FA
+		adminGroup.PUT("/2fa-policy/:role", controllers.SetTwoFactorPolicy) // Require 2FA for a role, or stop requiring it
+
+		// Earned badges waiting to be minted on chain, for the relayer that sends them
+		adminGroup.GET("/badge-mints", controllers.GetBadgeMints)       // Badge mints by status, oldest first
+		adminGroup.PUT("/badge-mints/:id", controllers.UpdateBadgeMint) // Report a mint sent or failed, or retry it
+
+		// Login lockouts
+		adminGroup.GET("/lockouts", controllers.GetLockoutEvents) // Audit trail of lockouts
+		adminGroup.DELETE("/lockouts", controllers.ClearLockout)  // Lift a lockout early
+	}
+}
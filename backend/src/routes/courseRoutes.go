@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"Delingo/src/controllers"
+	"Delingo/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CourseRoutes(r *gin.RouterGroup) {
+	// Anyone can browse published courses; logged-in learners see their own progress
+	r.GET("/courses", controllers.GetCourses)                                  // List published courses
+	r.GET("/courses/:id", middleware.OptionalJWTAuth(), controllers.GetCourse) // Course tree with locked and unlocked skills
+
+	// Taking lessons acts on the logged-in learner
+	learnGroup := r.Group("")
+	learnGroup.Use(middleware.JWTAuthMiddleware(), middleware.RequireTwoFactorPolicy())
+	{
+		learnGroup.GET("/progress", controllers.GetProgress)                        // Progress in each course started
+		learnGroup.GET("/courses/:id/progress", controllers.GetCourseProgress)      // Progress in a course by unit, skill and lesson
+		learnGroup.POST("/lessons/:id/sessions", controllers.StartLessonSession)    // Start a lesson and get its exercises
+		learnGroup.GET("/lesson-sessions/:id", controllers.GetLessonSession)        // Resume a lesson session
+		learnGroup.POST("/lesson-sessions/:id/answers", controllers.AnswerExercise) // Answer an exercise and get it graded
+		learnGroup.POST("/practice", controllers.StartPractice)                     // Practise the words and sentences due for review
+		learnGroup.GET("/streak", controllers.GetStreak)                            // Current streak and freezes, in the learner's time zone
+		learnGroup.GET("/streak/calendar", controllers.GetStreakCalendar)           // Practised and frozen days in a month
+		learnGroup.GET("/xp", controllers.GetXP)                                    // XP today, this week and in all, with the daily goal
+		learnGroup.GET("/xp/history", controllers.GetXPHistory)                     // XP by day, for charts
+		learnGroup.GET("/xp/leaderboard", controllers.GetXPLeaderboard)             // This week's league table among friends
+	}
+}
+
+func ContentRoutes(r *gin.RouterGroup) {
+	// Writing course content needs content:author
+	contentGroup := r.Group("/content")
+	contentGroup.Use(middleware.JWTAuthMiddleware(), middleware.RequireTwoFactorPolicy(), middleware.RequirePermission(middleware.PermContentAuthor))
+	{
+		// Courses
+		contentGroup.GET("/courses", controllers.GetAuthoringCourses)    // List every course, published or not
+		contentGroup.POST("/courses", controllers.CreateCourse)          // Start a course for a language pair
+		contentGroup.GET("/courses/:id", controllers.GetAuthoringCourse) // Full course tree with exercises
+		contentGroup.PATCH("/courses/:id", controllers.UpdateCourse)     // Update a course, or publish it
+		contentGroup.DELETE("/courses/:id", controllers.DeleteCourse)    // Delete a course and everything in it
+
+		// Units
+		contentGroup.POST("/courses/:id/units", controllers.CreateUnit)        // Add a unit to a course
+		contentGroup.PUT("/courses/:id/units/order", controllers.ReorderUnits) // Reorder a course's units
+		contentGroup.PATCH("/units/:id", controllers.UpdateUnit)               // Update a unit
+		contentGroup.DELETE("/units/:id", controllers.DeleteUnit)              // Delete a unit
+
+		// Skills
+		contentGroup.POST("/units/:id/skills", controllers.CreateSkill)                  // Add a skill to a unit
+		contentGroup.PUT("/units/:id/skills/order", controllers.ReorderSkills)           // Reorder a unit's skills
+		contentGroup.PATCH("/skills/:id", controllers.UpdateSkill)                       // Update a skill
+		contentGroup.DELETE("/skills/:id", controllers.DeleteSkill)                      // Delete a skill
+		contentGroup.PUT("/skills/:id/prerequisites", controllers.SetSkillPrerequisites) // Set the skills that unlock a skill
+
+		// Lessons
+		contentGroup.POST("/skills/:id/lessons", controllers.CreateLesson)        // Add a lesson to a skill
+		contentGroup.PUT("/skills/:id/lessons/order", controllers.ReorderLessons) // Reorder a skill's lessons
+		contentGroup.PATCH("/lessons/:id", controllers.UpdateLesson)              // Update a lesson
+		contentGroup.DELETE("/lessons/:id", controllers.DeleteLesson)             // Delete a lesson
+
+		// Exercises
+		contentGroup.POST("/lessons/:id/exercises", controllers.CreateExercise)        // Add an exercise to a lesson
+		contentGroup.PUT("/lessons/:id/exercises/order", controllers.ReorderExercises) // Reorder a lesson's exercises
+		contentGroup.PATCH("/exercises/:id", controllers.UpdateExercise)               // Update an exercise
+		contentGroup.DELETE("/exercises/:id", controllers.DeleteExercise)              // Delete an exercise
+
+		// Badges, awarded when their criteria rule holds
+		contentGroup.GET("/badges", controllers.GetBadges)          // List every badge
+		contentGroup.POST("/badges", controllers.CreateBadge)       // Add a badge with a rule such as "streak >= 30"
+		contentGroup.PUT("/badges/:id", controllers.UpdateBadge)    // Replace a badge's details and rule
+		contentGroup.DELETE("/badges/:id", controllers.DeleteBadge) // Delete a badge
+	}
+}
@@ -2,30 +2,94 @@ package routes
 
 import (
 	"Delingo/src/controllers"
+	"Delingo/src/middleware"
+	"Delingo/src/storage"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes(router *mux.Router) {
-	// Email-based User Routes
-	router.HandleFunc("/api/users", controllers.RegisterUser).Methods("POST")      // Email user registration
-	router.HandleFunc("/api/users/{id}", controllers.GetUser).Methods("GET")       // Get email user profile
-	router.HandleFunc("/api/users/{id}", controllers.UpdateUser).Methods("PUT")    // Update email user profile
-	router.HandleFunc("/api/users/{id}", controllers.DeleteUser).Methods("DELETE") // Delete email user
+// RegisterRoutes sets up the shared middleware and every API route on r
+func RegisterRoutes(r *gin.Engine) {
+	// Every request is logged, recovered from panics, checked for CORS and
+	// answered with JSON errors
+	r.Use(middleware.RequestLogger(), middleware.Recovery(), middleware.CORS(), middleware.ErrorHandler())
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(middleware.NotFound)
+	r.NoMethod(middleware.MethodNotAllowed)
+
+	// Health check
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "Hello, World!")
+	})
+
+	// Uploads kept on local disk are served by the API itself
+	if local, ok := storage.Default.(*storage.LocalStore); ok {
+		r.Static("/uploads", local.Dir)
+	}
+
+	api := r.Group("/api")
+	// Everything an admin does while acting as a user is audited
+	api.Use(middleware.AuditImpersonation())
+	AuthRoutes(api)
+	UserRoutes(api)
+	ProfileRoutes(api)
+	ForumRoutes(api)
+	SocialRoutes(api)
+	CourseRoutes(api)
+	ContentRoutes(api)
+	AccountRoutes(api)
+	AdminRoutes(api)
+}
+
+func AuthRoutes(r *gin.RouterGroup) {
+	// Email login and the flows around it
+	r.POST("/signup", controllers.Signup)                  // Register with email and password, and log in
+	r.POST("/login", controllers.Login)                    // Log in with email and password
+	r.POST("/login/2fa", controllers.LoginTwoFactor)       // Finish a login with a TOTP or recovery code
+	r.POST("/refresh", controllers.RefreshSession)         // Swap a refresh token for new tokens
+	r.POST("/logout", controllers.Logout)                  // End the session of a refresh token
+	r.POST("/verify-email", controllers.VerifyEmail)       // Confirm an email from its emailed token
+	r.POST("/password/forgot", controllers.ForgotPassword) // Email a password reset link
+	r.POST("/password/reset", controllers.ResetPassword)   // Set a new password from a reset token
 
 	// Solana-specific routes
-	router.HandleFunc("/api/solana/register", controllers.RegisterSolanaUser).Methods("POST")
-	router.HandleFunc("/api/solana/{id}", controllers.GetSolanaUser).Methods("GET")
+	r.GET("/solana/nonce", controllers.GetSolanaNonce)                                         // Issue a Solana sign-in nonce
+	r.POST("/solana/verify", controllers.VerifySolanaSignIn)                                   // Verify a signed Solana message and log in
+	r.GET("/solana/address/:address", middleware.OptionalJWTAuth(), controllers.GetSolanaUser) // Get user by Solana wallet address, if its owner shows wallets
 
 	// Ethereum Wallet User Routes
-	router.HandleFunc("/api/wallet/register", controllers.RegisterWalletUser).Methods("POST") // Register wallet user
-	router.HandleFunc("/api/wallet/{id}", controllers.UpdateWalletAddress).Methods("PUT")     // Update wallet address for user
-	router.HandleFunc("/api/wallet/{id}", controllers.GetUser).Methods("GET")                 // Get user profile by wallet address
+	r.GET("/wallet/nonce", controllers.GetWalletNonce)                                         // Issue a Sign-In With Ethereum nonce
+	r.POST("/wallet/verify", controllers.VerifyWalletSignIn)                                   // Verify a signed SIWE message and log in
+	r.GET("/wallet/address/:address", middleware.OptionalJWTAuth(), controllers.GetWalletUser) // Get user by Ethereum wallet address, if its owner shows wallets
+}
+
+func UserRoutes(r *gin.RouterGroup) {
+	// Email-based User Routes
+	r.POST("/users", controllers.RegisterUser) // Email user registration
+	r.GET("/users/:id", controllers.GetUser)   // Get user
 
+	// Changing an account needs to be that user, or an admin
+	userGroup := r.Group("/users")
+	userGroup.Use(middleware.JWTAuthMiddleware(), middleware.NoImpersonation(), middleware.RequireTwoFactorPolicy())
+	{
+		userGroup.PUT("/:id", controllers.UpdateUser)            // Update user
+		userGroup.DELETE("/:id", controllers.DeleteUser)         // Erase user, keeping an anonymous tombstone
+		userGroup.GET("/:id/export", controllers.ExportUserData) // Download all of a user's data
+	}
 }
-func SetupRoutes(router *gin.Engine) {
-	// Profile Routes
-	router.GET("/api/profile", controllers.GetUserProfile)    // Get profile
-	router.PUT("/api/profile", controllers.UpdateUserProfile) // Update profile
+
+func ProfileRoutes(r *gin.RouterGroup) {
+	// Profile Routes act on the logged-in user
+	profileGroup := r.Group("/profile")
+	profileGroup.Use(middleware.JWTAuthMiddleware(), middleware.RequireTwoFactorPolicy())
+	{
+		profileGroup.GET("", controllers.GetUserProfile)         // Get profile
+		profileGroup.PATCH("", controllers.UpdateUserProfile)    // Update the fields sent
+		profileGroup.POST("/avatar", controllers.UploadAvatar)   // Upload a new avatar
+		profileGroup.DELETE("/avatar", controllers.DeleteAvatar) // Remove the avatar
+	}
+
+	// Public profiles are open to everyone; logged-in viewers may see more
+	r.GET("/profiles/:username", middleware.OptionalJWTAuth(), controllers.GetPublicProfile)
 }
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"Delingo/src/controllers"
+	"Delingo/src/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SocialRoutes(r *gin.RouterGroup) {
+	// Anyone can see who follows whom
+	r.GET("/users/:id/followers", controllers.GetFollowers) // Users following a user
+	r.GET("/users/:id/following", controllers.GetFollowing) // Users a user follows
+
+	// Following and the feed act on the logged-in user
+	socialGroup := r.Group("")
+	socialGroup.Use(middleware.JWTAuthMiddleware(), middleware.RequireTwoFactorPolicy())
+	{
+		socialGroup.POST("/users/:id/follow", controllers.FollowUser)     // Follow a user
+		socialGroup.DELETE("/users/:id/follow", controllers.UnfollowUser) // Stop following a user
+		socialGroup.GET("/friends", controllers.GetFriends)               // Users who follow each other with the caller
+		socialGroup.GET("/feed", controllers.GetFeed)                     // Friends' recent activity
+	}
+}
@@ -0,0 +1,121 @@
+// srs/srs.go
+package srs
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// The scheduler is SM-2: each item has an ease factor that grows when it is
+// recalled easily and shrinks when it is not, and the interval to the next
+// review is multiplied by the ease after every successful review. Everything
+// here is pure: the time and randomness come from the caller.
+
+const (
+	DefaultEase = 2.5 // ease of a new item
+	MinEase     = 1.3 // ease never drops below this, or reviews would never space out
+)
+
+// Review qualities, on SM-2's scale of 0 to 5
+const (
+	QualityWrong   = 1 // not recalled
+	QualitySlip    = 3 // recalled with a typo or a missed accent
+	QualityCorrect = 4 // recalled
+	QualityPerfect = 5 // recalled without hesitation
+)
+
+// Card is the review schedule of one item
+type Card struct {
+	Ease        float64
+	Interval    int // days from the last review to the next
+	Repetitions int // successful reviews in a row
+	Lapses      int // times the item was forgotten
+	Due         time.Time
+}
+
+// NewCard is the schedule of an item first seen at now. It is due at once.
+func NewCard(now time.Time) Card {
+	return Card{Ease: DefaultEase, Due: now}
+}
+
+// Review returns the schedule after reviewing the card at now with quality
+// (0 to 5). Below 3 counts as forgotten: the item starts over the next day.
+func Review(card Card, quality int, now time.Time) Card {
+	q := min(max(quality, 0), 5)
+	if card.Ease < MinEase {
+		card.Ease = DefaultEase
+	}
+
+	if q < 3 {
+		card.Lapses++
+		card.Repetitions = 0
+		card.Interval = 1
+	} else {
+		card.Repetitions++
+		switch card.Repetitions {
+		case 1:
+			card.Interval = 1
+		case 2:
+			card.Interval = 6
+		default:
+			card.Interval = int(math.Round(float64(card.Interval) * card.Ease))
+		}
+	}
+
+	miss := float64(5 - q)
+	card.Ease = math.Max(MinEase, card.Ease+0.1-miss*(0.08+miss*0.02))
+	card.Due = now.AddDate(0, 0, card.Interval)
+	return card
+}
+
+// QualityOf grades a review from how an answer went: slip means it was right
+// but for a typo or accent
+func QualityOf(correct, slip bool) int {
+	switch {
+	case !correct:
+		return QualityWrong
+	case slip:
+		return QualitySlip
+	}
+	return QualityCorrect
+}
+
+// Weight is how much a due card needs practice. Weak cards, with a low ease
+// or many lapses, and cards long overdue weigh more. Cards not yet due weigh 0.
+func Weight(card Card, now time.Time) float64 {
+	if card.Due.After(now) {
+		return 0
+	}
+	weakness := 1 + float64(card.Lapses) + 2*(DefaultEase-math.Min(card.Ease, DefaultEase))
+	overdue := now.Sub(card.Due).Hours() / 24
+	return weakness * (1 + overdue/float64(max(card.Interval, 1)))
+}
+
+// Pick chooses up to n due cards for a practice session, returning their
+// indices. Cards are drawn at random in proportion to their Weight, so weak
+// words come up most but not only; the same rng state gives the same pick.
+func Pick(cards []Card, n int, now time.Time, rng *rand.Rand) []int {
+	// Weighted sampling without replacement: each card's key is u^(1/w) for a
+	// uniform u, and the n largest keys win
+	type keyed struct {
+		index int
+		key   float64
+	}
+	var candidates []keyed
+	for i, card := range cards {
+		w := Weight(card, now)
+		if w <= 0 {
+			continue
+		}
+		candidates = append(candidates, keyed{i, math.Pow(rng.Float64(), 1/w)})
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].key > candidates[b].key })
+
+	picked := []int{}
+	for _, c := range candidates[:min(n, len(candidates))] {
+		picked = append(picked, c.index)
+	}
+	return picked
+}
@@ -0,0 +1,195 @@
+package srs
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func TestReview(t *testing.T) {
+	tests := []struct {
+		name    string
+		card    Card
+		quality int
+		want    Card
+	}{
+		{
+			name:    "first review",
+			card:    NewCard(now),
+			quality: QualityCorrect,
+			want:    Card{Ease: 2.5, Interval: 1, Repetitions: 1},
+		},
+		{
+			name:    "second review",
+			card:    Card{Ease: 2.5, Interval: 1, Repetitions: 1},
+			quality: QualityCorrect,
+			want:    Card{Ease: 2.5, Interval: 6, Repetitions: 2},
+		},
+		{
+			name:    "third review multiplies by the ease",
+			card:    Card{Ease: 2.5, Interval: 6, Repetitions: 2},
+			quality: QualityCorrect,
+			want:    Card{Ease: 2.5, Interval: 15, Repetitions: 3},
+		},
+		{
+			name:    "interval is rounded",
+			card:    Card{Ease: 2.5, Interval: 15, Repetitions: 3},
+			quality: QualityCorrect,
+			want:    Card{Ease: 2.5, Interval: 38, Repetitions: 4},
+		},
+		{
+			name:    "perfect recall raises the ease",
+			card:    Card{Ease: 2.5, Interval: 6, Repetitions: 2},
+			quality: QualityPerfect,
+			want:    Card{Ease: 2.6, Interval: 15, Repetitions: 3},
+		},
+		{
+			name:    "a slip lowers the ease but keeps the streak",
+			card:    Card{Ease: 2.5, Interval: 6, Repetitions: 2},
+			quality: QualitySlip,
+			want:    Card{Ease: 2.36, Interval: 15, Repetitions: 3},
+		},
+		{
+			name:    "a lapse starts the item over",
+			card:    Card{Ease: 2.5, Interval: 38, Repetitions: 4, Lapses: 1},
+			quality: QualityWrong,
+			want:    Card{Ease: 1.96, Interval: 1, Repetitions: 0, Lapses: 2},
+		},
+		{
+			name:    "ease is clamped at MinEase",
+			card:    Card{Ease: 1.4, Interval: 6, Repetitions: 2},
+			quality: QualityWrong,
+			want:    Card{Ease: MinEase, Interval: 1, Repetitions: 0, Lapses: 1},
+		},
+		{
+			name:    "ease stays at MinEase",
+			card:    Card{Ease: MinEase, Interval: 1, Repetitions: 0, Lapses: 3},
+			quality: 0,
+			want:    Card{Ease: MinEase, Interval: 1, Repetitions: 0, Lapses: 4},
+		},
+		{
+			name:    "quality above 5 counts as 5",
+			card:    Card{Ease: 2.5, Interval: 1, Repetitions: 1},
+			quality: 9,
+			want:    Card{Ease: 2.6, Interval: 6, Repetitions: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Review(tt.card, tt.quality, now)
+			if math.Abs(got.Ease-tt.want.Ease) > 1e-9 {
+				t.Errorf("Ease = %v, want %v", got.Ease, tt.want.Ease)
+			}
+			if got.Interval != tt.want.Interval || got.Repetitions != tt.want.Repetitions || got.Lapses != tt.want.Lapses {
+				t.Errorf("Interval, Repetitions, Lapses = %d, %d, %d, want %d, %d, %d",
+					got.Interval, got.Repetitions, got.Lapses, tt.want.Interval, tt.want.Repetitions, tt.want.Lapses)
+			}
+			if want := now.AddDate(0, 0, tt.want.Interval); !got.Due.Equal(want) {
+				t.Errorf("Due = %v, want %v", got.Due, want)
+			}
+		})
+	}
+}
+
+func TestQualityOf(t *testing.T) {
+	tests := []struct {
+		correct, slip bool
+		want          int
+	}{
+		{false, false, QualityWrong},
+		{false, true, QualityWrong},
+		{true, true, QualitySlip},
+		{true, false, QualityCorrect},
+	}
+	for _, tt := range tests {
+		if got := QualityOf(tt.correct, tt.slip); got != tt.want {
+			t.Errorf("QualityOf(%v, %v) = %d, want %d", tt.correct, tt.slip, got, tt.want)
+		}
+	}
+}
+
+func TestWeight(t *testing.T) {
+	due := Card{Ease: DefaultEase, Interval: 6, Repetitions: 2, Due: now}
+	if w := Weight(Card{Ease: DefaultEase, Interval: 6, Due: now.Add(time.Hour)}, now); w != 0 {
+		t.Errorf("Weight of a card not yet due = %v, want 0", w)
+	}
+	if w := Weight(due, now); w <= 0 {
+		t.Errorf("Weight of a due card = %v, want more than 0", w)
+	}
+
+	lapsed := due
+	lapsed.Lapses = 3
+	if Weight(lapsed, now) <= Weight(due, now) {
+		t.Error("a card with lapses should weigh more than one without")
+	}
+	hard := due
+	hard.Ease = MinEase
+	if Weight(hard, now) <= Weight(due, now) {
+		t.Error("a card with a low ease should weigh more than an easy one")
+	}
+	overdue := due
+	overdue.Due = now.AddDate(0, 0, -10)
+	if Weight(overdue, now) <= Weight(due, now) {
+		t.Error("an overdue card should weigh more than one just due")
+	}
+}
+
+func TestPick(t *testing.T) {
+	cards := []Card{
+		{Ease: DefaultEase, Interval: 6, Due: now.AddDate(0, 0, -1)},
+		{Ease: DefaultEase, Interval: 6, Due: now.AddDate(0, 0, 2)}, // not due
+		{Ease: MinEase, Interval: 1, Lapses: 4, Due: now},
+		{Ease: 2.1, Interval: 15, Due: now.AddDate(0, 0, -20)},
+		{Ease: DefaultEase, Interval: 1, Due: now.Add(-time.Hour)},
+		{Ease: 1.8, Interval: 3, Lapses: 1, Due: now.AddDate(0, 0, -3)},
+	}
+
+	first := Pick(cards, 3, now, rand.New(rand.NewSource(42)))
+	second := Pick(cards, 3, now, rand.New(rand.NewSource(42)))
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Pick with the same seed = %v then %v", first, second)
+	}
+	if len(first) != 3 {
+		t.Errorf("Pick(3) picked %d cards", len(first))
+	}
+	seen := map[int]bool{}
+	for _, i := range first {
+		if i == 1 {
+			t.Error("Pick chose a card that isn't due")
+		}
+		if seen[i] {
+			t.Errorf("Pick chose card %d twice", i)
+		}
+		seen[i] = true
+	}
+
+	// Asking for more than are due gives every due card
+	all := Pick(cards, 10, now, rand.New(rand.NewSource(1)))
+	if len(all) != 5 {
+		t.Errorf("Pick(10) picked %v, want the 5 due cards", all)
+	}
+	if got := Pick(nil, 10, now, rand.New(rand.NewSource(1))); len(got) != 0 {
+		t.Errorf("Pick of no cards = %v", got)
+	}
+}
+
+// Weak cards come up more often than strong ones over many sessions
+func TestPickFavoursWeakCards(t *testing.T) {
+	cards := []Card{
+		{Ease: DefaultEase, Interval: 6, Due: now},
+		{Ease: MinEase, Interval: 6, Lapses: 5, Due: now},
+	}
+	rng := rand.New(rand.NewSource(7))
+	counts := [2]int{}
+	for i := 0; i < 1000; i++ {
+		counts[Pick(cards, 1, now, rng)[0]]++
+	}
+	if counts[1] <= counts[0] {
+		t.Errorf("picks of strong and weak cards = %v, want the weak one picked more", counts)
+	}
+}
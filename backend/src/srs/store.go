@@ -0,0 +1,103 @@
+// srs/store.go
+package srs
+
+import (
+	"Delingo/src/grading"
+	"Delingo/src/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Item is a word or sentence met in an exercise
+type Item struct {
+	Kind string // models.ReviewWord or models.ReviewSentence
+	Text string
+}
+
+// Store keeps learners' review items in Postgres
+type Store struct {
+	DB *gorm.DB
+}
+
+// Record reviews the items a learner met in an exercise, in language, with
+// quality at now. Items met for the first time are added. Each item is locked
+// while its schedule changes so two answers at once can't lose a review.
+func (s Store) Record(userID int, language string, exerciseID uint, items []Item, quality int, now time.Time) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			text := strings.TrimSpace(item.Text)
+			key := grading.Normalize(text, language)
+			if key == "" {
+				continue
+			}
+
+			row := models.ReviewItem{
+				UserID: userID, Language: language, Key: key, Kind: item.Kind, Text: text,
+				ExerciseID: exerciseID, Ease: DefaultEase, DueAt: now,
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+				return err
+			}
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("user_id = ? AND language = ? AND key = ?", userID, language, key).
+				First(&row).Error
+			if err != nil {
+				return err
+			}
+
+			card := Review(cardOf(row), quality, now)
+			err = tx.Model(&row).Updates(map[string]interface{}{
+				"text":             text,
+				"exercise_id":      exerciseID,
+				"ease":             card.Ease,
+				"interval_days":    card.Interval,
+				"repetitions":      card.Repetitions,
+				"lapses":           card.Lapses,
+				"due_at":           card.Due,
+				"last_reviewed_at": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Due returns up to limit of a learner's items due at now, most overdue first,
+// in one language or, with "", in any. Items whose exercise has since been
+// deleted are left out, as they can't be practised.
+func (s Store) Due(userID int, language string, now time.Time, limit int) ([]models.ReviewItem, error) {
+	query := s.DB.Where("user_id = ? AND due_at <= ?", userID, now).
+		Where("EXISTS (SELECT 1 FROM exercises WHERE exercises.id = review_items.exercise_id)").
+		Order("due_at, id").Limit(limit)
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	items := []models.ReviewItem{}
+	err := query.Find(&items).Error
+	return items, err
+}
+
+// Cards returns the schedules of stored items, in the same order
+func Cards(items []models.ReviewItem) []Card {
+	cards := make([]Card, len(items))
+	for i, item := range items {
+		cards[i] = cardOf(item)
+	}
+	return cards
+}
+
+// cardOf returns the schedule of a stored item
+func cardOf(item models.ReviewItem) Card {
+	return Card{
+		Ease:        item.Ease,
+		Interval:    item.IntervalDays,
+		Repetitions: item.Repetitions,
+		Lapses:      item.Lapses,
+		Due:         item.DueAt,
+	}
+}
@@ -0,0 +1,62 @@
+// storage/local.go
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps files in a directory on disk. The API serves Dir at BaseURL,
+// which suits development and single-server deployments.
+type LocalStore struct {
+	Dir     string
+	BaseURL string
+}
+
+// Put writes data to a temporary file and renames it into place, so readers
+// never see a partial file
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes the file for key
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// URL returns BaseURL joined with key
+func (s *LocalStore) URL(key string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + key
+}
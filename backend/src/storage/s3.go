@@ -0,0 +1,214 @@
+// storage/s3.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Store keeps files in an S3 bucket. It speaks the plain REST API signed with
+// Signature Version 4, so it also works with S3-compatible servers like MinIO
+// (use PathStyle for those).
+type S3Store struct {
+	Endpoint  string // e.g. "https://s3.eu-west-1.amazonaws.com" or "http://localhost:9000"
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PublicURL string // base URL objects are served from; defaults to the bucket URL
+	PathStyle bool   // address the bucket as <endpoint>/<bucket> instead of <bucket>.<host>
+
+	Client *http.Client // defaults to a client with a 30s timeout
+}
+
+// Put uploads data to key
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return s.do(ctx, http.MethodPut, key, data, header)
+}
+
+// Delete removes key from the bucket
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	return s.do(ctx, http.MethodDelete, key, nil, http.Header{})
+}
+
+// URL returns the public URL of key
+func (s *S3Store) URL(key string) string {
+	base := s.PublicURL
+	if base == "" {
+		base = s.bucketURL()
+	}
+	return strings.TrimSuffix(base, "/") + "/" + encodePath(key)
+}
+
+// bucketURL returns the bucket's base URL for the configured addressing style
+func (s *S3Store) bucketURL() string {
+	endpoint := strings.TrimSuffix(s.Endpoint, "/")
+	if s.PathStyle {
+		return endpoint + "/" + s.Bucket
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint + "/" + s.Bucket
+	}
+	u.Host = s.Bucket + "." + u.Host
+	return u.String()
+}
+
+// do sends a signed request for key and turns any non-2xx answer into an error
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, method, s.bucketURL()+"/"+encodePath(key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.ContentLength = int64(len(body))
+	signV4(req, body, s.AccessKey, s.SecretKey, s.Region, "s3", time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 %s %s: %s: %s", method, key, resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// signV4 adds AWS Signature Version 4 headers to req, signing the host and every
+// header already set on it
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Canonical headers: lowercase names, sorted, with trimmed values
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalPath := req.URL.EscapedPath()
+	if canonicalPath == "" {
+		canonicalPath = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodePath URI-encodes each segment of an object key
+func encodePath(key string) string {
+	return uriEncode(key, false)
+}
+
+// uriEncode percent-encodes everything but unreserved characters (and '/' unless encodeSlash)
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
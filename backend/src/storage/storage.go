@@ -0,0 +1,62 @@
+// storage/storage.go
+package storage
+
+import (
+	"Delingo/src/config"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BlobStore keeps uploaded files and knows the public URL of each.
+// Implementations must be safe for concurrent use.
+type BlobStore interface {
+	// Put stores data under key, replacing anything already there
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns where clients can fetch key
+	URL(key string) string
+}
+
+// Default is the store used by the application, set by Init
+var Default BlobStore
+
+// Init builds Default from the STORAGE_DRIVER setting: "local" (the default)
+// writes files under STORAGE_DIR and "s3" uses an S3-compatible bucket
+func Init() error {
+	switch config.StorageDriver {
+	case "", "local":
+		Default = &LocalStore{Dir: config.StorageDir, BaseURL: config.StorageBaseURL}
+	case "s3":
+		if config.S3Bucket == "" || config.S3AccessKey == "" || config.S3SecretKey == "" {
+			return errors.New("S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required for the s3 storage driver")
+		}
+		Default = &S3Store{
+			Endpoint:  config.S3Endpoint,
+			Region:    config.S3Region,
+			Bucket:    config.S3Bucket,
+			AccessKey: config.S3AccessKey,
+			SecretKey: config.S3SecretKey,
+			PublicURL: config.S3PublicURL,
+			PathStyle: config.S3PathStyle,
+		}
+	default:
+		return fmt.Errorf("unknown storage driver %q", config.StorageDriver)
+	}
+	return nil
+}
+
+// validateKey rejects keys that could escape the store's namespace
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid key %q", key)
+		}
+	}
+	return nil
+}
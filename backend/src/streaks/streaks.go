@@ -0,0 +1,143 @@
+// streaks/streaks.go
+package streaks
+
+import "time"
+
+// Days are calendar dates in the learner's time zone, written as "2006-01-02".
+// They are counted as dates, never as 24-hour spans, so a day that a DST change
+// makes 23 or 25 hours long is still one day. Everything here is pure: the
+// time comes from the caller.
+
+const (
+	MaxFreezes  = 2 // streak freezes a learner can hold at once
+	freezeEvery = 7 // days of streak that earn a freeze
+	dayLayout   = "2006-01-02"
+)
+
+// Milestones are the streak lengths worth celebrating
+var Milestones = []int{3, 7, 14, 30, 50, 100, 200, 365, 500, 1000}
+
+// State is a learner's streak
+type State struct {
+	Current  int
+	Longest  int
+	Freezes  int    // freezes held, each covering one missed day
+	LastDay  string // the last day practised or frozen; "" before the first practice
+	Timezone string // the time zone LastDay was counted in
+}
+
+// Outcome is the streak after a check or a practice, and what happened to it
+type Outcome struct {
+	State        State
+	Frozen       []string // missed days that freezes covered, oldest first
+	Lost         int      // the length of a streak broken by missed days, or 0
+	Extended     bool     // the practice added a day
+	FreezeEarned bool
+	Milestone    int // the milestone reached, or 0
+}
+
+// Day returns the date at t in loc
+func Day(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(dayLayout)
+}
+
+// DaysBetween counts the calendar days from a to b, negative if b comes first
+func DaysBetween(a, b string) int {
+	ta, errA := time.Parse(dayLayout, a)
+	tb, errB := time.Parse(dayLayout, b)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	// Both are midnight UTC, where every day has 24 hours
+	return int(tb.Sub(ta).Hours() / 24)
+}
+
+// AddDays returns the date n days after day
+func AddDays(day string, n int) string {
+	t, err := time.Parse(dayLayout, day)
+	if err != nil {
+		return day
+	}
+	return t.AddDate(0, 0, n).Format(dayLayout)
+}
+
+// Check settles the days missed since the last practice, as of now in loc:
+// freezes cover them if there are enough, otherwise the streak is lost.
+// Today itself isn't missed until it is over.
+func Check(state State, now time.Time, loc *time.Location) Outcome {
+	return settle(state, missedDays(state, now, loc))
+}
+
+// Practise counts a day of practice at now in loc, after settling any missed
+// days. Practising again the same day changes nothing.
+func Practise(state State, now time.Time, loc *time.Location) Outcome {
+	day := Day(now, loc)
+	o := settle(state, missedDays(state, now, loc))
+	// Flying west can bring back a date already counted; it isn't counted twice
+	if o.State.LastDay != "" && o.State.Current > 0 && DaysBetween(o.State.LastDay, day) <= 0 {
+		return o
+	}
+
+	s := &o.State
+	s.Current++
+	s.Longest = max(s.Longest, s.Current)
+	s.LastDay = day
+	s.Timezone = loc.String()
+	o.Extended = true
+	if s.Current%freezeEvery == 0 && s.Freezes < MaxFreezes {
+		s.Freezes++
+		o.FreezeEarned = true
+	}
+	for _, m := range Milestones {
+		if m == s.Current {
+			o.Milestone = m
+		}
+	}
+	return o
+}
+
+// NextMilestone returns the first milestone above current, or 0 past the last
+func NextMilestone(current int) int {
+	for _, m := range Milestones {
+		if m > current {
+			return m
+		}
+	}
+	return 0
+}
+
+// missedDays counts the days between LastDay and now, in loc, without
+// practice. After a change of time zone it is the smaller of the counts in the
+// old and new zones, so a date skipped by flying east isn't missed.
+func missedDays(state State, now time.Time, loc *time.Location) int {
+	if state.LastDay == "" {
+		return 0
+	}
+	missed := DaysBetween(state.LastDay, Day(now, loc)) - 1
+	if state.Timezone != "" && state.Timezone != loc.String() {
+		if previous, err := time.LoadLocation(state.Timezone); err == nil {
+			missed = min(missed, DaysBetween(state.LastDay, Day(now, previous))-1)
+		}
+	}
+	return missed
+}
+
+// settle covers the missed days after LastDay with freezes or, without enough,
+// breaks the streak
+func settle(state State, missed int) Outcome {
+	o := Outcome{State: state}
+	if state.Current == 0 || missed <= 0 {
+		return o
+	}
+	if missed > state.Freezes {
+		o.Lost = state.Current
+		o.State.Current = 0
+		return o
+	}
+	for i := 1; i <= missed; i++ {
+		o.Frozen = append(o.Frozen, AddDays(state.LastDay, i))
+	}
+	o.State.Freezes -= missed
+	o.State.LastDay = AddDays(state.LastDay, missed)
+	return o
+}
@@ -0,0 +1,257 @@
+package streaks
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func zone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return loc
+}
+
+func at(t *testing.T, loc *time.Location, value string) time.Time {
+	t.Helper()
+	tm, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func TestDays(t *testing.T) {
+	if got := DaysBetween("2024-03-09", "2024-03-11"); got != 2 {
+		t.Errorf("DaysBetween across a DST change = %d, want 2", got)
+	}
+	if got := DaysBetween("2024-03-01", "2024-02-28"); got != -2 {
+		t.Errorf("DaysBetween backwards over a leap day = %d, want -2", got)
+	}
+	if got := AddDays("2024-02-28", 1); got != "2024-02-29" {
+		t.Errorf("AddDays into a leap day = %s", got)
+	}
+	if got := AddDays("2024-12-31", 1); got != "2025-01-01" {
+		t.Errorf("AddDays into a new year = %s", got)
+	}
+	// 23:30 on the 1st in Los Angeles is already the 2nd in UTC
+	la := zone(t, "America/Los_Angeles")
+	if got := Day(at(t, la, "2024-06-01 23:30"), la); got != "2024-06-01" {
+		t.Errorf("Day = %s, want the local date", got)
+	}
+}
+
+// Practice on consecutive dates keeps the streak, however long the days in
+// between are
+func TestPractiseAcrossDST(t *testing.T) {
+	ny := zone(t, "America/New_York")
+	tests := []struct {
+		name        string
+		last, today string
+	}{
+		{"23-hour day, late to late", "2024-03-09 23:30", "2024-03-10 23:30"},
+		{"23-hour day, early to late", "2024-03-09 00:30", "2024-03-10 23:59"},
+		{"23-hour day, late to early", "2024-03-09 23:30", "2024-03-10 00:10"},
+		{"25-hour day, early to late", "2024-11-02 00:30", "2024-11-03 23:30"},
+		{"25-hour day, late to early", "2024-11-02 23:30", "2024-11-03 00:10"},
+		{"day after the 25-hour day", "2024-11-03 00:10", "2024-11-04 23:50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := Practise(State{}, at(t, ny, tt.last), ny)
+			o := Practise(first.State, at(t, ny, tt.today), ny)
+			if !o.Extended || o.State.Current != 2 || o.Lost != 0 || len(o.Frozen) != 0 {
+				t.Errorf("Practise = %+v, want the streak extended to 2", o)
+			}
+		})
+	}
+
+	// Skipping the short day itself is a missed day
+	first := Practise(State{}, at(t, ny, "2024-03-09 12:00"), ny)
+	if o := Practise(first.State, at(t, ny, "2024-03-11 00:30"), ny); o.Lost != 1 || o.State.Current != 1 {
+		t.Errorf("Practise after skipping 2024-03-10 = %+v, want the streak lost", o)
+	}
+}
+
+func TestPractiseWhileTravelling(t *testing.T) {
+	la, tokyo := zone(t, "America/Los_Angeles"), zone(t, "Asia/Tokyo")
+
+	// Flying east skips most of a date: 8am on the 3rd in Tokyo is still the
+	// 2nd in Los Angeles, so the 2nd isn't missed
+	east := State{Current: 5, Longest: 5, LastDay: "2024-06-01", Timezone: la.String()}
+	o := Practise(east, at(t, tokyo, "2024-06-03 08:00"), tokyo)
+	if !o.Extended || o.State.Current != 6 || len(o.Frozen) != 0 || o.Lost != 0 {
+		t.Errorf("Practise after flying east = %+v, want the streak extended without freezes", o)
+	}
+	if o.State.LastDay != "2024-06-03" || o.State.Timezone != tokyo.String() {
+		t.Errorf("after flying east LastDay, Timezone = %s, %s", o.State.LastDay, o.State.Timezone)
+	}
+
+	// Flying west brings back a date already practised; it isn't counted twice
+	west := State{Current: 5, Longest: 5, LastDay: "2024-06-03", Timezone: tokyo.String()}
+	steps := []struct {
+		when     string
+		extended bool
+		current  int
+	}{
+		{"2024-06-02 18:00", false, 5}, // the 2nd again, in Los Angeles
+		{"2024-06-03 18:00", false, 5}, // the 3rd, already counted in Tokyo
+		{"2024-06-04 18:00", true, 6},
+	}
+	state := west
+	for _, step := range steps {
+		o := Practise(state, at(t, la, step.when), la)
+		if o.Extended != step.extended || o.State.Current != step.current || o.Lost != 0 || len(o.Frozen) != 0 {
+			t.Errorf("Practise at %s after flying west = %+v, want extended %v to %d", step.when, o, step.extended, step.current)
+		}
+		state = o.State
+	}
+
+	// A day really missed is missed in both zones
+	missed := State{Current: 5, Longest: 5, LastDay: "2024-06-01", Timezone: la.String()}
+	if o := Practise(missed, at(t, tokyo, "2024-06-04 08:00"), tokyo); o.Lost != 5 {
+		t.Errorf("Practise two days later after flying east = %+v, want the streak lost", o)
+	}
+}
+
+func TestFreezes(t *testing.T) {
+	utc := time.UTC
+	tests := []struct {
+		name    string
+		state   State
+		when    string
+		check   bool // Check rather than Practise
+		want    State
+		frozen  []string
+		lost    int
+		extends bool
+	}{
+		{
+			name:    "freezes cover a gap",
+			state:   State{Current: 10, Longest: 10, Freezes: 2, LastDay: "2024-06-01", Timezone: "UTC"},
+			when:    "2024-06-04 09:00",
+			want:    State{Current: 11, Longest: 11, Freezes: 0, LastDay: "2024-06-04", Timezone: "UTC"},
+			frozen:  []string{"2024-06-02", "2024-06-03"},
+			extends: true,
+		},
+		{
+			name:    "too few freezes lose the streak",
+			state:   State{Current: 10, Longest: 12, Freezes: 2, LastDay: "2024-06-01", Timezone: "UTC"},
+			when:    "2024-06-05 09:00",
+			want:    State{Current: 1, Longest: 12, Freezes: 2, LastDay: "2024-06-05", Timezone: "UTC"},
+			lost:    10,
+			extends: true,
+		},
+		{
+			name:  "today isn't missed until it is over",
+			state: State{Current: 4, Longest: 4, Freezes: 1, LastDay: "2024-06-01", Timezone: "UTC"},
+			when:  "2024-06-02 23:59",
+			check: true,
+			want:  State{Current: 4, Longest: 4, Freezes: 1, LastDay: "2024-06-01", Timezone: "UTC"},
+		},
+		{
+			name:   "a check spends freezes on days already missed",
+			state:  State{Current: 4, Longest: 4, Freezes: 1, LastDay: "2024-06-01", Timezone: "UTC"},
+			when:   "2024-06-03 08:00",
+			check:  true,
+			want:   State{Current: 4, Longest: 4, Freezes: 0, LastDay: "2024-06-02", Timezone: "UTC"},
+			frozen: []string{"2024-06-02"},
+		},
+		{
+			name:  "a check without freezes loses the streak",
+			state: State{Current: 4, Longest: 4, LastDay: "2024-06-01", Timezone: "UTC"},
+			when:  "2024-06-03 08:00",
+			check: true,
+			want:  State{Current: 0, Longest: 4, LastDay: "2024-06-01", Timezone: "UTC"},
+			lost:  4,
+		},
+		{
+			name:    "practising twice a day counts once",
+			state:   State{Current: 4, Longest: 4, LastDay: "2024-06-01", Timezone: "UTC"},
+			when:    "2024-06-01 20:00",
+			want:    State{Current: 4, Longest: 4, LastDay: "2024-06-01", Timezone: "UTC"},
+			extends: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o Outcome
+			if tt.check {
+				o = Check(tt.state, at(t, utc, tt.when), utc)
+			} else {
+				o = Practise(tt.state, at(t, utc, tt.when), utc)
+			}
+			if o.State != tt.want {
+				t.Errorf("State = %+v, want %+v", o.State, tt.want)
+			}
+			if !reflect.DeepEqual(o.Frozen, tt.frozen) {
+				t.Errorf("Frozen = %v, want %v", o.Frozen, tt.frozen)
+			}
+			if o.Lost != tt.lost || o.Extended != tt.extends {
+				t.Errorf("Lost, Extended = %d, %v, want %d, %v", o.Lost, o.Extended, tt.lost, tt.extends)
+			}
+		})
+	}
+}
+
+func TestFreezeEarning(t *testing.T) {
+	now := time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		current     int
+		freezes     int
+		wantFreezes int
+		wantEarned  bool
+	}{
+		{"first week", 6, 0, 1, true},
+		{"second week", 13, 1, 2, true},
+		{"capped at MaxFreezes", 20, MaxFreezes, MaxFreezes, false},
+		{"not a whole week", 7, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := State{Current: tt.current, Longest: tt.current, Freezes: tt.freezes, LastDay: "2024-06-01", Timezone: "UTC"}
+			o := Practise(state, now, time.UTC)
+			if o.State.Freezes != tt.wantFreezes || o.FreezeEarned != tt.wantEarned {
+				t.Errorf("Freezes, FreezeEarned = %d, %v, want %d, %v", o.State.Freezes, o.FreezeEarned, tt.wantFreezes, tt.wantEarned)
+			}
+		})
+	}
+}
+
+func TestMilestones(t *testing.T) {
+	now := time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		current int // before practising
+		want    int
+	}{
+		{0, 0},
+		{2, 3},
+		{3, 0},
+		{6, 7},
+		{29, 30},
+		{364, 365},
+		{999, 1000},
+		{1000, 0},
+	}
+	for _, tt := range tests {
+		state := State{Current: tt.current, Longest: tt.current, LastDay: "2024-06-01", Timezone: "UTC"}
+		if tt.current == 0 {
+			state = State{}
+		}
+		if got := Practise(state, now, time.UTC).Milestone; got != tt.want {
+			t.Errorf("Milestone after practising on a streak of %d = %d, want %d", tt.current, got, tt.want)
+		}
+	}
+
+	next := map[int]int{0: 3, 3: 7, 7: 14, 364: 365, 999: 1000, 1000: 0, 5000: 0}
+	for current, want := range next {
+		if got := NextMilestone(current); got != want {
+			t.Errorf("NextMilestone(%d) = %d, want %d", current, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// utils/auth.go
+package utils
+
+import (
+	"Delingo/src/config"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password accepted at signup
+const MinPasswordLength = 8
+
+// HashPassword hashes a plain-text password with bcrypt
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	// bcrypt silently ignores everything past 72 bytes, so reject it instead
+	if len(password) > 72 {
+		return "", errors.New("password must be at most 72 bytes")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPasswordHash reports whether password matches the stored bcrypt hash
+func CheckPasswordHash(password, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// GenerateToken issues a short-lived signed JWT carrying the user's ID in the
+// user_id claim, their session's ID in the sid claim and their role
+func GenerateToken(userID int, sessionID uint, role string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"sid":     sessionID,
+		"role":    role,
+		"iat":     now.Unix(),
+		"exp":     now.Add(config.AccessTokenTTL).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.JWTSecret))
+}
+
+// ParseToken verifies a JWT signature and expiry and returns its claims
+func ParseToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Only accept the HMAC family we sign with
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(config.JWTSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("token claims are invalid")
+	}
+	return claims, nil
+}
+
+// twoFactorPurpose marks tokens that only let the holder submit a second factor
+const twoFactorPurpose = "2fa"
+
+// GenerateTwoFactorToken issues the short-lived token handed out after a correct
+// password when the account still needs a TOTP code. It carries no session, so
+// it is never accepted as an access token.
+func GenerateTwoFactorToken(userID int) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"purpose": twoFactorPurpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(config.TwoFactorTokenTTL).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.JWTSecret))
+}
+
+// ParseTwoFactorToken verifies a token from GenerateTwoFactorToken and returns its user ID
+func ParseTwoFactorToken(tokenString string) (int, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok || claims["purpose"] != twoFactorPurpose {
+		return 0, errors.New("token claims are invalid")
+	}
+	return int(userID), nil
+}
+
+// GenerateOpaqueToken returns a new random token (for refresh tokens and emailed
+// links) and the hash to store for it
+func GenerateOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the SHA-256 hex digest stored in place of an opaque token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomHex returns n cryptographically random bytes encoded as hex
+func RandomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,141 @@
+// utils/avatar.go
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/png"
+)
+
+// AvatarSizes are the square thumbnails generated for every avatar, by name
+var AvatarSizes = map[string]int{
+	"small":  64,
+	"medium": 128,
+	"large":  256,
+}
+
+// AvatarContentTypes are the upload formats accepted, as sniffed from the data
+var AvatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Upper bounds on a decoded upload, checked before any pixels are decoded so a
+// small file can't expand into a huge image. At 4 bytes a pixel, decoding the
+// largest allowed image and cropping it takes about 128MB.
+const (
+	maxAvatarEdge   = 4096
+	maxAvatarPixels = maxAvatarEdge * maxAvatarEdge
+)
+
+// ErrUnsupportedImage is returned for uploads that aren't an accepted image
+var ErrUnsupportedImage = errors.New("avatar must be a JPEG, PNG or GIF image")
+
+// ProcessAvatar checks an uploaded image, crops it to a centred square and
+// re-encodes it as a JPEG thumbnail for each of AvatarSizes. Re-encoding drops
+// metadata and anything else hidden in the original file.
+func ProcessAvatar(data []byte) (map[string][]byte, error) {
+	if !AvatarContentTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxAvatarEdge || cfg.Height > maxAvatarEdge ||
+		cfg.Width*cfg.Height > maxAvatarPixels {
+		return nil, fmt.Errorf("avatar must be at most %dx%d pixels", maxAvatarEdge, maxAvatarEdge)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	square := cropSquare(img)
+
+	thumbnails := make(map[string][]byte, len(AvatarSizes))
+	for name, size := range AvatarSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resizeBox(square, size), &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		thumbnails[name] = buf.Bytes()
+	}
+	return thumbnails, nil
+}
+
+// cropSquare returns the centred square of img flattened onto white, since
+// JPEG has no transparency
+func cropSquare(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	edge := b.Dx()
+	if b.Dy() < edge {
+		edge = b.Dy()
+	}
+	origin := image.Pt(b.Min.X+(b.Dx()-edge)/2, b.Min.Y+(b.Dy()-edge)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, edge, edge))
+	draw.Draw(square, square.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(square, square.Bounds(), img, origin, draw.Over)
+	return square
+}
+
+// resizeBox scales a square image to size x size by averaging the source pixels
+// that fall in each destination pixel, which keeps downscaled photos smooth
+func resizeBox(src *image.RGBA, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	edge := src.Bounds().Dx()
+
+	for dy := 0; dy < size; dy++ {
+		y0, y1 := span(dy, size, edge)
+		for dx := 0; dx < size; dx++ {
+			x0, x1 := span(dx, size, edge)
+
+			var r, g, b, a, n uint32
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i+0] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span returns the source pixel range [start, end) covered by destination pixel
+// i of n, always at least one pixel wide
+func span(i, n, edge int) (int, int) {
+	start := i * edge / n
+	end := (i + 1) * edge / n
+	if end <= start {
+		end = start + 1
+	}
+	if end > edge {
+		end = edge
+		if start >= end {
+			start = end - 1
+		}
+	}
+	return start, end
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"Delingo/src/models"
 	"database/sql"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -33,10 +34,238 @@ func InitDB() error {
 		return err // Return error if ping fails
 	}
 
+	// Free-form profile settings become JSONB; set the old text aside first
+	if err := setAsideProfileSettings(); err != nil {
+		return err
+	}
+
 	// Auto-migrate GORM models
-	if err := GormDB.AutoMigrate(&models.User{}, &models.Profile{}); err != nil {
+	if err := GormDB.AutoMigrate(&models.User{}, &models.Profile{}, &models.AuthNonce{}, &models.UserIdentity{}, &models.Session{}, &models.UserToken{}, &models.RecoveryCode{}, &models.RolePolicy{}, &models.LoginThrottle{}, &models.LockoutEvent{},
+		&models.Thread{}, &models.Post{}, &models.Comment{}, &models.Vote{}, &models.Badge{}, &models.UserBadge{}, &models.BadgeMint{},
+		&models.Follow{}, &models.Activity{}, &models.UserTombstone{},
+		&models.UserBan{}, &models.AuditLog{},
+		&models.Course{}, &models.Unit{}, &models.Skill{}, &models.SkillPrerequisite{}, &models.Lesson{}, &models.Exercise{},
+		&models.LessonSession{}, &models.ExerciseAnswer{}, &models.Progress{}, &models.ReviewItem{},
+		&models.Streak{}, &models.StreakDay{}, &models.XPEntry{}); err != nil {
 		return err // Return error if migration fails
 	}
 
+	// Move the old single-address columns into user_identities
+	if err := migrateLegacyIdentities(); err != nil {
+		return err
+	}
+
+	// Carry the set-aside settings over and bring stored preferences up to the current schema
+	if err := migrateProfileSettings(); err != nil {
+		return err
+	}
+
+	// Give accounts registered before profiles were created automatically their profile
+	if err := backfillProfiles(); err != nil {
+		return err
+	}
+
+	// Usernames address public profiles, so every account needs a unique one
+	if err := migrateUsernames(); err != nil {
+		return err
+	}
+
+	// Levels used to count finished lessons; they now come from the XP ledger
+	if err := backfillLevels(); err != nil {
+		return err
+	}
+
 	return nil // No error, successful initialization
 }
+
+// migrateLegacyIdentities copies the email and wallet columns that used to live on
+// users into user_identities, then drops the wallet columns. Wallet addresses are
+// only marked verified when the account was created by a signed wallet sign-in.
+func migrateLegacyIdentities() error {
+	legacy := []struct {
+		column   string
+		subject  string
+		provider string
+	}{
+		{"email", "lower(email)", models.ProviderEmail},
+		{"ethereum_wallet_addr", "ethereum_wallet_addr", models.ProviderEthereum},
+		{"solana_wallet_addr", "solana_wallet_addr", models.ProviderSolana},
+	}
+
+	migrator := GormDB.Migrator()
+	for _, l := range legacy {
+		if !migrator.HasColumn(&models.User{}, l.column) {
+			continue
+		}
+
+		query := `INSERT INTO user_identities (user_id, provider, subject, verified_at, created_at)
+				  SELECT id, @provider, ` + l.subject + `,
+				         CASE WHEN registration_method = @provider AND @provider <> 'email' THEN created_at END,
+				         created_at
+				  FROM users WHERE ` + l.column + ` IS NOT NULL AND ` + l.column + ` <> ''
+				  ON CONFLICT DO NOTHING`
+		if err := GormDB.Exec(query, map[string]interface{}{"provider": l.provider}).Error; err != nil {
+			return err
+		}
+
+		// Email stays on users as the contact address; wallets now live only in identities
+		if l.provider != models.ProviderEmail {
+			if err := migrator.DropColumn(&models.User{}, l.column); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// profileSettingsColumns are the profile columns that moved from text to JSONB
+var profileSettingsColumns = []string{"preferences", "social_links"}
+
+// setAsideProfileSettings renames text settings columns to <name>_legacy so
+// AutoMigrate can create them again as JSONB
+func setAsideProfileSettings() error {
+	migrator := GormDB.Migrator()
+	if !migrator.HasTable(&models.Profile{}) {
+		return nil
+	}
+	columns, err := migrator.ColumnTypes(&models.Profile{})
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		for _, name := range profileSettingsColumns {
+			if column.Name() == name && !strings.EqualFold(column.DatabaseTypeName(), "jsonb") {
+				if err := migrator.RenameColumn(&models.Profile{}, name, name+"_legacy"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// migrateProfileSettings converts set-aside text settings and upgrades stored
+// preferences to PreferencesVersion, rewriting each row that changes
+func migrateProfileSettings() error {
+	migrator := GormDB.Migrator()
+	for _, name := range profileSettingsColumns {
+		legacy := name + "_legacy"
+		if !migrator.HasColumn(&models.Profile{}, legacy) {
+			continue
+		}
+
+		var rows []struct {
+			ID    int
+			Value sql.NullString
+		}
+		if err := GormDB.Table("profiles").Select("id", legacy+" AS value").Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			// Scanning parses and upgrades the old text; invalid content falls back to defaults
+			var value interface{}
+			if name == "preferences" {
+				var prefs models.Preferences
+				if err := prefs.Scan(row.Value.String); err != nil {
+					return err
+				}
+				value = prefs
+			} else {
+				var links models.SocialLinks
+				links.Scan(row.Value.String)
+				if links.Validate() != nil {
+					links = models.SocialLinks{}
+				}
+				value = links
+			}
+			if err := GormDB.Model(&models.Profile{}).Where("id = ?", row.ID).UpdateColumn(name, value).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := migrator.DropColumn(&models.Profile{}, legacy); err != nil {
+			return err
+		}
+	}
+
+	// Rows saved under an older schema version are upgraded as they are read; persist that
+	var outdated []models.Profile
+	err := GormDB.Select("id", "preferences").
+		Where("COALESCE((preferences->>'version')::int, 0) < ?", models.PreferencesVersion).
+		Find(&outdated).Error
+	if err != nil {
+		return err
+	}
+	for _, profile := range outdated {
+		if err := GormDB.Model(&models.Profile{}).Where("id = ?", profile.ID).
+			UpdateColumn("preferences", profile.Preferences).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillProfiles creates an empty profile for every user without one and
+// clears the NULLs older rows may hold
+func backfillProfiles() error {
+	err := GormDB.Exec(`INSERT INTO profiles (user_id, created_at, updated_at)
+		SELECT id, NOW(), NOW() FROM users u
+		WHERE NOT EXISTS (SELECT 1 FROM profiles p WHERE p.user_id = u.id)`).Error
+	if err != nil {
+		return err
+	}
+	return GormDB.Exec(`UPDATE profiles SET
+		bio = COALESCE(bio, ''), avatar_url = COALESCE(avatar_url, ''),
+		location = COALESCE(location, ''), website = COALESCE(website, '')
+		WHERE bio IS NULL OR avatar_url IS NULL OR location IS NULL OR website IS NULL`).Error
+}
+
+// migrateUsernames gives accounts without a username their default one, renames
+// accounts holding another account's default username or sharing a username
+// (ignoring case) with an older account, and then makes usernames unique
+func migrateUsernames() error {
+	err := GormDB.Exec(`UPDATE users SET username = 'user' || id WHERE username IS NULL OR username = ''`).Error
+	if err != nil {
+		return err
+	}
+	err = GormDB.Exec(`UPDATE users SET username = username || '_' || id
+		WHERE username ~* '^user[0-9]+$' AND lower(username) <> 'user' || id`).Error
+	if err != nil {
+		return err
+	}
+	err = GormDB.Exec(`UPDATE users u SET username = u.username || '_' || u.id
+		WHERE EXISTS (SELECT 1 FROM users o WHERE lower(o.username) = lower(u.username) AND o.id < u.id)`).Error
+	if err != nil {
+		return err
+	}
+	// Accounts get their default username right after they are inserted, so
+	// empty usernames are left out of the index
+	return GormDB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower
+		ON users (lower(username)) WHERE username <> ''`).Error
+}
+
+// backfillLevels sets every profile's level from its all-time XP, for
+// profiles whose level was counted from finished lessons before the XP ledger.
+// Level n needs 25n(n+1) XP, as in the controllers' levelForXP, so the level
+// of T XP is the largest n with 25n(n+1) <= T: floor((sqrt(625 + 100T) - 25) / 50).
+// Awards keep levels current, so after the first run nothing changes.
+func backfillLevels() error {
+	return GormDB.Exec(`UPDATE profiles p SET level = l.level
+		FROM (SELECT p.user_id, floor((sqrt(625 + 100 * COALESCE(SUM(x.amount), 0)::float8) - 25) / 50)::int AS level
+			FROM profiles p LEFT JOIN xp_entries x ON x.user_id = p.user_id
+			GROUP BY p.user_id) l
+		WHERE l.user_id = p.user_id AND p.level IS DISTINCT FROM l.level`).Error
+}
+
+// PromoteAdmins gives the admin role to the accounts with the given verified
+// emails, so a fresh deployment has someone who can assign roles
+func PromoteAdmins(emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	verified := GormDB.Model(&models.UserIdentity{}).Select("user_id").
+		Where("provider = ? AND subject IN ? AND verified_at IS NOT NULL", models.ProviderEmail, emails)
+	return GormDB.Model(&models.User{}).
+		Where("id IN (?)", verified).
+		Update("role", models.RoleAdmin).Error
+}
@@ -0,0 +1,177 @@
+// utils/siwe.go
+package utils
+
+import (
+	"Delingo/src/config"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// clockSkew is how far in the future an Issued At time may be
+const clockSkew = time.Minute
+
+// SignInMessage is a parsed EIP-4361 (Sign-In With Ethereum) message
+type SignInMessage struct {
+	Domain         string
+	Chain          string // the account type named in the header, e.g. "Ethereum"
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        string
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// ParseSignInMessage parses the plain-text message a wallet was asked to sign
+func ParseSignInMessage(message string) (*SignInMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	if len(lines) < 2 {
+		return nil, errors.New("message is too short")
+	}
+
+	// Header: "<domain> wants you to sign in with your <Chain> account:"
+	header, ok := strings.CutSuffix(lines[0], " account:")
+	if !ok {
+		return nil, errors.New("message header is malformed")
+	}
+	domain, chain, ok := strings.Cut(header, " wants you to sign in with your ")
+	if !ok || domain == "" || chain == "" {
+		return nil, errors.New("message header is malformed")
+	}
+	// The domain may be prefixed with a scheme
+	if _, rest, found := strings.Cut(domain, "://"); found {
+		domain = rest
+	}
+
+	m := &SignInMessage{Domain: domain, Chain: chain, Address: strings.TrimSpace(lines[1])}
+	if m.Address == "" {
+		return nil, errors.New("message address is missing")
+	}
+
+	var issuedAt string
+	inResources := false
+	for _, line := range lines[2:] {
+		if line == "" {
+			continue
+		}
+		if inResources {
+			if resource, found := strings.CutPrefix(line, "- "); found {
+				m.Resources = append(m.Resources, resource)
+				continue
+			}
+			inResources = false
+		}
+
+		key, value, found := strings.Cut(line, ": ")
+		if !found && line == "Resources:" {
+			inResources = true
+			continue
+		}
+
+		var err error
+		switch {
+		case found && key == "URI":
+			m.URI = value
+		case found && key == "Version":
+			m.Version = value
+		case found && key == "Chain ID":
+			m.ChainID = value
+		case found && key == "Nonce":
+			m.Nonce = value
+		case found && key == "Issued At":
+			issuedAt = value
+		case found && key == "Expiration Time":
+			m.ExpirationTime, err = parseMessageTime(value)
+		case found && key == "Not Before":
+			m.NotBefore, err = parseMessageTime(value)
+		case found && key == "Request ID":
+			m.RequestID = value
+		case m.URI == "" && m.Statement == "":
+			// Free text before the fields is the statement
+			m.Statement = line
+		default:
+			return nil, fmt.Errorf("unexpected line in message: %q", line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", strings.ToLower(key), err)
+		}
+	}
+
+	if m.Nonce == "" {
+		return nil, errors.New("message nonce is missing")
+	}
+	if issuedAt == "" {
+		return nil, errors.New("message issued at is missing")
+	}
+	t, err := parseMessageTime(issuedAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issued at: %v", err)
+	}
+	m.IssuedAt = *t
+
+	return m, nil
+}
+
+// Validate checks the message was meant for this server and is currently valid
+func (m *SignInMessage) Validate(chain string, now time.Time) error {
+	if !strings.EqualFold(m.Chain, chain) {
+		return fmt.Errorf("message is not a %s sign-in", chain)
+	}
+	if !strings.EqualFold(m.Domain, config.SIWEDomain) {
+		return errors.New("message domain does not match")
+	}
+	if m.IssuedAt.After(now.Add(clockSkew)) {
+		return errors.New("message is issued in the future")
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return errors.New("message has expired")
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return errors.New("message is not valid yet")
+	}
+	return nil
+}
+
+// VerifyEthereumSignature checks an EIP-191 personal_sign signature over message
+// and returns the address that produced it
+func VerifyEthereumSignature(message, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return common.Address{}, errors.New("signature is not valid hex")
+	}
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errors.New("signature has the wrong length")
+	}
+
+	// Wallets return V as 27/28, go-ethereum expects 0/1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return common.Address{}, errors.New("could not recover signer")
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// parseMessageTime parses an RFC 3339 timestamp from a sign-in message
+func parseMessageTime(value string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,65 @@
+// utils/solana.go
+package utils
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// DecodeBase58 decodes a Bitcoin-alphabet base58 string, as used for Solana keys
+func DecodeBase58(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty base58 string")
+	}
+
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	zeros := 0
+	for i, c := range []byte(s) {
+		digit := -1
+		for j := 0; j < len(base58Alphabet); j++ {
+			if base58Alphabet[j] == c {
+				digit = j
+				break
+			}
+		}
+		if digit < 0 {
+			return nil, errors.New("invalid base58 character")
+		}
+		// Each leading '1' encodes a leading zero byte
+		if digit == 0 && i == zeros {
+			zeros++
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+// IsSolanaAddress reports whether address is a base58-encoded ed25519 public key
+func IsSolanaAddress(address string) bool {
+	key, err := DecodeBase58(address)
+	return err == nil && len(key) == ed25519.PublicKeySize
+}
+
+// VerifySolanaSignature checks a base58 ed25519 signature over message against
+// the base58 public key in address
+func VerifySolanaSignature(message, signature, address string) error {
+	key, err := DecodeBase58(address)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("address is not a Solana public key")
+	}
+	sig, err := DecodeBase58(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return errors.New("signature is not a valid ed25519 signature")
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(key), []byte(message), sig) {
+		return errors.New("signature does not match address")
+	}
+	return nil
+}
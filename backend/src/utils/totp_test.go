@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC 6238 appendix B vectors for SHA-1. The RFC gives 8 digits; a
+// 6-digit code is the same value mod 10^6, its last 6 digits.
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	// Secrets are accepted in lower case too
+	if got, _ := TOTPCode(strings.ToLower(rfc6238Secret), time.Unix(59, 0)); got != "287082" {
+		t.Errorf("TOTPCode with a lower-case secret = %s, want 287082", got)
+	}
+	if _, err := TOTPCode("not base32!", time.Unix(59, 0)); err == nil {
+		t.Error("TOTPCode with an invalid secret didn't fail")
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0) // step 37037037
+	step := now.Unix() / totpPeriod
+	tests := []struct {
+		name     string
+		codeAt   time.Time
+		wantOK   bool
+		wantStep int64
+	}{
+		{"current step", now, true, step},
+		{"previous step", now.Add(-totpPeriod * time.Second), true, step - 1},
+		{"next step", now.Add(totpPeriod * time.Second), true, step + 1},
+		{"two steps behind", now.Add(-2 * totpPeriod * time.Second), false, 0},
+		{"two steps ahead", now.Add(2 * totpPeriod * time.Second), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, tt.codeAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotStep, ok := ValidateTOTP(rfc6238Secret, code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP = %d, %v, want %d, %v", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		code string
+		ok   bool
+	}{
+		{"050471", true},
+		{"050 471", true}, // as apps display it
+		{"50471", false},
+		{"0504711", false},
+		{"050472", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, ok := ValidateTOTP(rfc6238Secret, tt.code, now); ok != tt.ok {
+			t.Errorf("ValidateTOTP(%q) = %v, want %v", tt.code, ok, tt.ok)
+		}
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 160 bits is 32 base32 characters
+	if len(secret) != 32 {
+		t.Errorf("secret %q has %d characters, want 32", secret, len(secret))
+	}
+	if _, err := TOTPCode(secret, time.Now()); err != nil {
+		t.Errorf("generated secret can't make codes: %v", err)
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	got := TOTPProvisioningURI("Delingo", "ana@example.com", rfc6238Secret)
+	want := "otpauth://totp/Delingo:ana@example.com?algorithm=SHA1&digits=6&issuer=Delingo&period=30&secret=" + rfc6238Secret
+	if got != want {
+		t.Errorf("TOTPProvisioningURI = %s, want %s", got, want)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		// 80 bits is 16 base32 characters, in groups of 4
+		if len(code) != 19 || strings.Count(code, "-") != 3 || code != strings.ToLower(code) {
+			t.Errorf("recovery code %q isn't shaped like k3vq-8mzt-w2p9-a7rc", code)
+		}
+		if NormalizeRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", " "))) != code {
+			t.Errorf("recovery code %q doesn't survive normalizing", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q repeated", code)
+		}
+		seen[code] = true
+	}
+
+	tests := map[string]string{
+		"K3VQ8MZTW2P9A7RC":    "k3vq-8mzt-w2p9-a7rc",
+		"k3vq-8mzt-w2p9-a7rc": "k3vq-8mzt-w2p9-a7rc",
+		" k3vq 8mzt-W2P9a7rc": "k3vq-8mzt-w2p9-a7rc",
+	}
+	for in, want := range tests {
+		if got := NormalizeRecoveryCode(in); got != want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -42,3 +42,18 @@ func DeployContract(contractABI abi.ABI, contractAddress string) *common.Address
 	address := common.HexToAddress(contractAddress)
 	return &address
 }
+
+// badgeNFTABI describes the BadgeNFT functions the server calls
+const badgeNFTABI = `[{"type":"function","name":"mintBadge","stateMutability":"nonpayable","outputs":[],"inputs":[
+	{"name":"to","type":"address"},{"name":"name","type":"string"},
+	{"name":"description","type":"string"},{"name":"criteria","type":"string"}]}]`
+
+// MintBadgeCallData encodes a BadgeNFT.mintBadge call minting a badge to the
+// wallet at to
+func MintBadgeCallData(to, name, description, criteria string) ([]byte, error) {
+	contractABI, err := abi.JSON(strings.NewReader(badgeNFTABI))
+	if err != nil {
+		return nil, err
+	}
+	return contractABI.Pack("mintBadge", common.HexToAddress(to), name, description, criteria)
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// word left-pads hex to a 32-byte ABI word
+func word(hexValue string) string {
+	return strings.Repeat("0", 64-len(hexValue)) + hexValue
+}
+
+func TestMintBadgeCallData(t *testing.T) {
+	// The selector is the start of keccak256 of the function signature
+	selector := hex.EncodeToString(crypto.Keccak256([]byte("mintBadge(address,string,string,string)"))[:4])
+	if selector != "c2ed630e" {
+		t.Fatalf("selector = %s, want c2ed630e", selector)
+	}
+
+	to := "0x00000000000000000000000000000000000000Ab"
+	criteria := `{"rule":"streak >= 7","mint":true}` // more than one word
+	data, err := MintBadgeCallData(to, "Week", "", criteria)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	padded := hex.EncodeToString([]byte(criteria)) + strings.Repeat("0", 2*(64-len(criteria)))
+	want := selector +
+		word("ab") + // to
+		word("80") + // offset of name, after the four head words
+		word("c0") + // offset of description, after name's length and one data word
+		word("e0") + // offset of criteria, after description's length alone
+		word("4") + hex.EncodeToString([]byte("Week")) + strings.Repeat("0", 64-8) +
+		word("0") +
+		word(strconv.FormatInt(int64(len(criteria)), 16)) + padded
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("MintBadgeCallData =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMintBadgeCallDataUnicode(t *testing.T) {
+	// Strings are encoded as UTF-8 and their length counts bytes
+	data, err := MintBadgeCallData("0x0000000000000000000000000000000000000001", "Ñandú", "d", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := data[4+4*32:]
+	if got := hex.EncodeToString(name[:32]); got != word("7") {
+		t.Errorf("name length word = %s, want 7 bytes", got)
+	}
+	if got := string(name[32 : 32+7]); got != "Ñandú" {
+		t.Errorf("name = %q", got)
+	}
+}
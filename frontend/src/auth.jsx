@@ -1,17 +1,138 @@
 import React, { useState } from "react";
 import axios from "axios";
+import { BrowserProvider } from "ethers";
 
 // You will need to replace this with actual routes based on your backend API setup.
 const API_BASE_URL = "http://localhost:8080"; // Your backend base URL
 
+// Keep the short-lived access token and the refresh token that renews it
+const storeSession = (data) => {
+    localStorage.setItem("access_token", data.access_token);
+    localStorage.setItem("refresh_token", data.refresh_token);
+};
+
+// Accounts with two-factor authentication answer a login with a challenge;
+// ask for a code from the authenticator app (or a recovery code) to finish it
+const completeLogin = async (data) => {
+    if (!data.two_factor_required) {
+        storeSession(data);
+        return data;
+    }
+
+    const code = window.prompt("Enter the code from your authenticator app, or a recovery code");
+    if (!code) throw new Error("Two-factor code is required");
+    const isTotp = /^\d{6}$/.test(code.trim());
+    const response = await axios.post(`${API_BASE_URL}/api/login/2fa`, {
+        two_factor_token: data.two_factor_token,
+        ...(isTotp ? { code: code.trim() } : { recovery_code: code }),
+    });
+    storeSession(response.data);
+    return response.data;
+};
+
+// Sign in with MetaMask using a Sign-In With Ethereum (EIP-4361) message
+const signInWithEthereum = async () => {
+    if (!window.ethereum) {
+        throw new Error("MetaMask is not installed");
+    }
+    const provider = new BrowserProvider(window.ethereum);
+    const signer = await provider.getSigner();
+    const address = await signer.getAddress();
+    const { chainId } = await provider.getNetwork();
+
+    // The backend hands out a single-use nonce that must appear in the signed message
+    const { data } = await axios.get(`${API_BASE_URL}/api/wallet/nonce`);
+    const message = [
+        `${window.location.host} wants you to sign in with your Ethereum account:`,
+        address,
+        "",
+        "Sign in to Delingo.",
+        "",
+        `URI: ${window.location.origin}`,
+        "Version: 1",
+        `Chain ID: ${chainId}`,
+        `Nonce: ${data.nonce}`,
+        `Issued At: ${new Date().toISOString()}`,
+        `Expiration Time: ${data.expires_at}`,
+    ].join("\n");
+    const signature = await signer.signMessage(message);
+
+    const response = await axios.post(`${API_BASE_URL}/api/wallet/verify`, { message, signature });
+    return completeLogin(response.data);
+};
+
+// Encode bytes as base58, the encoding Solana uses for keys and signatures
+const BASE58_ALPHABET = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz";
+const encodeBase58 = (bytes) => {
+    let n = BigInt("0x" + (Array.from(bytes, (b) => b.toString(16).padStart(2, "0")).join("") || "0"));
+    let out = "";
+    while (n > 0n) {
+        out = BASE58_ALPHABET[Number(n % 58n)] + out;
+        n /= 58n;
+    }
+    for (const b of bytes) {
+        if (b !== 0) break;
+        out = "1" + out;
+    }
+    return out;
+};
+
+// Sign in with a Solana wallet (e.g. Phantom) by signing a server-issued nonce
+const signInWithSolana = async () => {
+    if (!window.solana) {
+        throw new Error("No Solana wallet found");
+    }
+    const { publicKey } = await window.solana.connect();
+
+    const { data } = await axios.get(`${API_BASE_URL}/api/solana/nonce`);
+    const message = [
+        `${window.location.host} wants you to sign in with your Solana account:`,
+        publicKey.toBase58(),
+        "",
+        "Sign in to Delingo.",
+        "",
+        `URI: ${window.location.origin}`,
+        "Version: 1",
+        `Nonce: ${data.nonce}`,
+        `Issued At: ${new Date().toISOString()}`,
+        `Expiration Time: ${data.expires_at}`,
+    ].join("\n");
+    const { signature } = await window.solana.signMessage(new TextEncoder().encode(message), "utf8");
+
+    const response = await axios.post(`${API_BASE_URL}/api/solana/verify`, {
+        message,
+        signature: encodeBase58(signature),
+    });
+    return completeLogin(response.data);
+};
+
+const handleSolanaSignIn = async () => {
+    try {
+        const data = await signInWithSolana();
+        console.log("Solana Sign In Success:", data);
+    } catch (error) {
+        console.error("Solana Sign In Failed:", error);
+    }
+};
+
+const handleEthereumSignIn = async () => {
+    try {
+        const data = await signInWithEthereum();
+        console.log("Wallet Sign In Success:", data);
+    } catch (error) {
+        console.error("Wallet Sign In Failed:", error);
+    }
+};
+
 const Login = () => {
     const [email, setEmail] = useState("");
     const [password, setPassword] = useState("");
 
     const handleEmailLogin = async () => {
         try {
-            const response = await axios.post(`${API_BASE_URL}/login/email`, { email, password });
-            console.log("Login Success:", response.data);
+            const response = await axios.post(`${API_BASE_URL}/api/login`, { email, password });
+            const data = await completeLogin(response.data);
+            console.log("Login Success:", data);
         } catch (error) {
             console.error("Login Failed:", error);
         }
@@ -40,10 +161,10 @@ const Login = () => {
                     Login with Email
                 </button>
                 <div className="text-center mb-4">or</div>
-                <button className="w-full py-2 mb-2 bg-yellow-500 text-white rounded flex items-center justify-center">
+                <button onClick={handleEthereumSignIn} className="w-full py-2 mb-2 bg-yellow-500 text-white rounded flex items-center justify-center">
                     <i className="fab fa-ethereum mr-2"></i> Connect with MetaMask
                 </button>
-                <button className="w-full py-2 bg-purple-500 text-white rounded flex items-center justify-center">
+                <button onClick={handleSolanaSignIn} className="w-full py-2 bg-purple-500 text-white rounded flex items-center justify-center">
                     <i className="fab fa-ethereum mr-2"></i> Connect with Solana
                 </button>
                 <div className="text-center mt-4">
@@ -60,7 +181,8 @@ const SignUp = () => {
 
     const handleEmailSignUp = async () => {
         try {
-            const response = await axios.post(`${API_BASE_URL}/signup/email`, { email, password });
+            const response = await axios.post(`${API_BASE_URL}/api/signup`, { email, password });
+            storeSession(response.data);
             console.log("Sign Up Success:", response.data);
         } catch (error) {
             console.error("Sign Up Failed:", error);
@@ -90,10 +212,10 @@ const SignUp = () => {
                     Sign Up with Email
                 </button>
                 <div className="text-center mb-4">or</div>
-                <button className="w-full py-2 mb-2 bg-yellow-500 text-white rounded flex items-center justify-center">
+                <button onClick={handleEthereumSignIn} className="w-full py-2 mb-2 bg-yellow-500 text-white rounded flex items-center justify-center">
                     <i className="fab fa-ethereum mr-2"></i> Connect with MetaMask
                 </button>
-                <button className="w-full py-2 bg-purple-500 text-white rounded flex items-center justify-center">
+                <button onClick={handleSolanaSignIn} className="w-full py-2 bg-purple-500 text-white rounded flex items-center justify-center">
                     <i className="fab fa-ethereum mr-2"></i> Connect with Solana
                 </button>
                 <div className="text-center mt-4">